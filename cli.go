@@ -0,0 +1,449 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"tb/internal/config"
+	"tb/internal/history"
+	"tb/internal/lint"
+	"tb/internal/search"
+)
+
+// subcommands are the non-interactive commands that operate on the config.
+var subcommands = map[string]func(cfg *config.Config, args []string) error{
+	"add":    runAdd,
+	"rm":     runRemove,
+	"edit":   runEdit,
+	"list":   runList,
+	"show":   runShow,
+	"import": runImport,
+	"export": runExport,
+	"trash":  runTrash,
+}
+
+// fileCommands work on the config files directly, so they still run when the
+// config doesn't load.
+var fileCommands = map[string]func(configPath string, args []string) error{
+	"lint":    runLint,
+	"restore": runRestore,
+}
+
+// commandFlags binds the editable command fields to flags on fs.
+type commandFlags struct {
+	name, command, steps, category, description, tags *string
+}
+
+func newCommandFlags(fs *flag.FlagSet) commandFlags {
+	return commandFlags{
+		name:        fs.String("name", "", "command name"),
+		command:     fs.String("command", "", "shell command"),
+		steps:       fs.String("steps", "", "comma separated command names to run in order, making a workflow"),
+		category:    fs.String("category", "", "category (tab)"),
+		description: fs.String("description", "", "description"),
+		tags:        fs.String("tags", "", "comma separated tags"),
+	}
+}
+
+// apply copies the flags that were set on the command line into cmd.
+func (f commandFlags) apply(fs *flag.FlagSet, cmd *config.Command) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "name":
+			cmd.Name = strings.TrimSpace(*f.name)
+		case "command":
+			cmd.Command = strings.TrimSpace(*f.command)
+		case "steps":
+			cmd.Steps = nil
+			for _, name := range strings.Split(*f.steps, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					cmd.Steps = append(cmd.Steps, name)
+				}
+			}
+		case "category":
+			cmd.Category = strings.TrimSpace(*f.category)
+		case "description":
+			cmd.Description = strings.TrimSpace(*f.description)
+		case "tags":
+			cmd.Tags = config.ParseTags(*f.tags)
+		}
+	})
+}
+
+// parseArgs parses flags that may come before or after a single positional
+// argument, e.g. "tb edit git-undo --category git". It returns the positional
+// argument, or "" if there was none.
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	var pos string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		pos, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if pos == "" && fs.NArg() > 0 {
+		pos = fs.Arg(0)
+		if fs.NArg() > 1 {
+			return "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args()[1:], " "))
+		}
+	} else if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return pos, nil
+}
+
+// parseFlags parses args for subcommands that take only flags.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	pos, err := parseArgs(fs, args)
+	if err == nil && pos != "" {
+		err = fmt.Errorf("unexpected argument %q", pos)
+	}
+	return err
+}
+
+// requireName parses args and returns the command name they must contain.
+func requireName(fs *flag.FlagSet, args []string) (string, error) {
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		fs.Usage()
+		return "", errors.New("missing command name")
+	}
+	return name, nil
+}
+
+// lookup returns the index of the named command or a not-found error.
+func lookup(cfg *config.Config, name string) (int, error) {
+	i := config.Find(cfg.Commands, name)
+	if i < 0 {
+		return -1, fmt.Errorf("no command named %q", name)
+	}
+	return i, nil
+}
+
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tb %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runAdd(cfg *config.Config, args []string) error {
+	fs := newFlagSet("add", "add [<name> | --name <name>] (--command <command> | --steps <a,b>) [--category <category>] [--description <text>] [--tags <a,b>]")
+	f := newCommandFlags(fs)
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var cmd config.Command
+	f.apply(fs, &cmd)
+	if name = strings.TrimSpace(name); name != "" {
+		if cmd.Name != "" && cmd.Name != name {
+			return fmt.Errorf("two names given: %q and --name %q", name, cmd.Name)
+		}
+		cmd.Name = name
+	}
+	if err := config.Validate(cfg.Commands, cmd, -1); err != nil {
+		return err
+	}
+	if err := cfg.Save(append(cfg.Commands, cmd)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Added %s\n", cmd.Name)
+	return nil
+}
+
+func runRemove(cfg *config.Config, args []string) error {
+	fs := newFlagSet("rm", "rm <name>")
+	name, err := requireName(fs, args)
+	if err != nil {
+		return err
+	}
+	i, err := lookup(cfg, name)
+	if err != nil {
+		return err
+	}
+
+	removed := cfg.Commands[i]
+	commands := append(cfg.Commands[:i:i], cfg.Commands[i+1:]...)
+	if err := cfg.Save(commands); err != nil {
+		return err
+	}
+	if err := cfg.MoveToTrash(removed); err != nil {
+		return fmt.Errorf("removed %s, but could not keep it in the trash: %w", name, err)
+	}
+	fmt.Fprintf(os.Stderr, "Removed %s (tb trash restore %s brings it back)\n", name, name)
+	return nil
+}
+
+func runEdit(cfg *config.Config, args []string) error {
+	fs := newFlagSet("edit", "edit <name> [--name <new name>] [--command <command>] [--steps <a,b>] [--category <category>] [--description <text>] [--tags <a,b>]")
+	f := newCommandFlags(fs)
+	name, err := requireName(fs, args)
+	if err != nil {
+		return err
+	}
+	i, err := lookup(cfg, name)
+	if err != nil {
+		return err
+	}
+	if fs.NFlag() == 0 {
+		return errors.New("nothing to change; pass at least one --field=value flag")
+	}
+
+	commands := append([]config.Command(nil), cfg.Commands...)
+	f.apply(fs, &commands[i])
+	if err := config.Validate(commands, commands[i], i); err != nil {
+		return err
+	}
+	config.RenameStep(commands, name, commands[i].Name)
+	if err := cfg.Save(commands); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Updated %s\n", commands[i].Name)
+	return nil
+}
+
+func runList(cfg *config.Config, args []string) error {
+	fs := newFlagSet("list", "list [--category <category>] [--query <text>] [--json | --format <template>]")
+	category := fs.String("category", "", "only list commands in this category or nested below it")
+	query := fs.String("query", "", "filter like the TUI search bar (fuzzy, #tag tokens)")
+	asJSON := fs.Bool("json", false, "print the full command records as JSON")
+	format := fs.String("format", "", "Go text/template for each command, e.g. '{{.Name}}\\t{{.Command}}'")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *asJSON && *format != "" {
+		return errors.New("--json and --format are mutually exclusive")
+	}
+
+	var commands []config.Command
+	for _, cmd := range cfg.Commands {
+		if *category == "" || config.InCategory(cmd.Category, *category) {
+			commands = append(commands, cmd)
+		}
+	}
+	if *query != "" {
+		// History only breaks ties, so a broken history file isn't fatal.
+		hist, _ := history.Load()
+		commands = search.Filter(commands, *query, hist.Scores(time.Now()))
+	}
+
+	switch {
+	case *asJSON:
+		if commands == nil {
+			commands = []config.Command{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false) // keep && and < > readable in commands
+		return enc.Encode(commands)
+	case *format != "":
+		return printFormatted(os.Stdout, commands, *format)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		cmd, _ = config.Resolve(cfg.Commands, cmd)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", cmd.Name, cmd.Category, firstLine(cmd.Command))
+	}
+	return w.Flush()
+}
+
+// formatEscapes turns the escapes people type in shell-quoted templates into
+// the characters they mean.
+var formatEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n")
+
+// printFormatted renders each command with a text/template, one per line.
+func printFormatted(w io.Writer, commands []config.Command, format string) error {
+	tmpl, err := template.New("format").Parse(formatEscapes.Replace(format))
+	if err != nil {
+		return fmt.Errorf("invalid --format: %w", err)
+	}
+	for _, cmd := range commands {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, cmd); err != nil {
+			return fmt.Errorf("invalid --format: %w", err)
+		}
+		out := b.String()
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		if _, err := io.WriteString(w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runLint checks every config file tb reads. It takes the config path rather
+// than a loaded config, since its job is to explain why loading fails.
+func runLint(configPath string, args []string) error {
+	fs := newFlagSet("lint", "lint")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	files, err := config.Files(configPath)
+	if err != nil {
+		return err
+	}
+	diags, err := lint.Files(files)
+	if err != nil {
+		return err
+	}
+	lint.Sort(diags, files)
+
+	errs := 0
+	for _, d := range diags {
+		fmt.Println(d)
+		if d.Severity == lint.Error {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%d error(s), %d warning(s)", errs, len(diags)-errs)
+	}
+	return nil
+}
+
+func runRestore(configPath string, args []string) error {
+	fs := newFlagSet("restore", "restore [--file <path>] [<n>]")
+	file := fs.String("file", "", "config file to restore (default: the global config)")
+	arg, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	path := *file
+	if path == "" {
+		if path, err = config.ResolvePath(configPath); err != nil {
+			return err
+		}
+	}
+
+	if arg == "" {
+		backups, err := config.Backups(path)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			return fmt.Errorf("no backups of %s", path)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, b := range backups {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", b.N, b.ModTime.Format("2006-01-02 15:04:05"), b.Path)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Run 'tb restore <n>' to roll back to one of these.")
+		return nil
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid backup number %q", arg)
+	}
+	if err := config.Restore(path, n); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Restored %s from backup %d; the replaced version is now backup 1\n", path, n)
+	return nil
+}
+
+func runTrash(cfg *config.Config, args []string) error {
+	const usage = "trash list | trash restore <name>"
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: tb %s\n", usage)
+		return errors.New("missing trash command")
+	}
+	switch args[0] {
+	case "list":
+		fs := newFlagSet("trash list", "trash list")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		entries, err := cfg.Trash()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Name, e.DeletedAt.Format("2006-01-02 15:04"), e.File, firstLine(e.Command.Command))
+		}
+		return w.Flush()
+	case "restore":
+		fs := newFlagSet("trash restore", "trash restore <name>")
+		name, err := requireName(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		cmd, err := cfg.RestoreFromTrash(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Restored %s to %s\n", cmd.Name, cmd.Origin)
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: tb %s\n", usage)
+		return fmt.Errorf("unknown trash command %q", args[0])
+	}
+}
+
+func runShow(cfg *config.Config, args []string) error {
+	fs := newFlagSet("show", "show <name>")
+	name, err := requireName(fs, args)
+	if err != nil {
+		return err
+	}
+	i, err := lookup(cfg, name)
+	if err != nil {
+		return err
+	}
+	// A workflow with broken steps is still shown, then reported.
+	cmd, err := config.Resolve(cfg.Commands, cfg.Commands[i])
+	printCommand(os.Stdout, cmd)
+	return err
+}
+
+func printCommand(w io.Writer, cmd config.Command) {
+	fmt.Fprintf(w, "Name:        %s\n", cmd.Name)
+	if cmd.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", cmd.Description)
+	}
+	if cmd.Category != "" {
+		fmt.Fprintf(w, "Category:    %s\n", cmd.Category)
+	}
+	if len(cmd.Tags) > 0 {
+		fmt.Fprintf(w, "Tags:        %s\n", strings.Join(cmd.Tags, ", "))
+	}
+	if cmd.Pinned {
+		fmt.Fprintf(w, "Pinned:      yes\n")
+	}
+	if cmd.IsWorkflow() {
+		fmt.Fprintf(w, "Steps:       %s\n", strings.Join(cmd.Steps, ", "))
+	}
+	fmt.Fprintf(w, "File:        %s\n", cmd.Origin)
+	fmt.Fprintf(w, "Command:\n%s\n", cmd.Command)
+}
+
+// firstLine returns the first line of s, marking any truncation.
+func firstLine(s string) string {
+	if line, _, found := strings.Cut(s, "\n"); found {
+		return line + " …"
+	}
+	return s
+}
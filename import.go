@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"tb/internal/config"
+	"tb/internal/fsutil"
+	"tb/internal/importer"
+	"tb/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// importCategory is the category given to commands imported from shell history.
+const importCategory = "imported"
+
+// snippetFormats are the file formats tb import and tb export understand.
+var snippetFormats = map[string]struct {
+	parse func(io.Reader) ([]config.Command, error)
+	write func(io.Writer, []config.Command) error
+}{
+	"navi": {importer.ParseNavi, importer.WriteNavi},
+	"pet":  {importer.ParsePet, importer.WritePet},
+}
+
+func runImport(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: tb import <history | navi <file> | pet <file>>")
+	}
+	if args[0] == "history" {
+		return importHistory(cfg, args[1:])
+	}
+	format, ok := snippetFormats[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown import source %q (supported: history, navi, pet)", args[0])
+	}
+
+	fs := newFlagSet("import "+args[0], "import "+args[0]+" <file>")
+	path, err := requireName(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	parsed, err := format.parse(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	known := make(map[string]bool)
+	taken := make(map[string]bool)
+	for _, cmd := range cfg.Commands {
+		known[cmd.Command] = true
+		taken[cmd.Name] = true
+	}
+	var fresh []config.Command
+	for _, cmd := range parsed {
+		if !known[cmd.Command] {
+			known[cmd.Command] = true
+			fresh = append(fresh, cmd)
+		}
+	}
+	fresh = importer.Named(fresh, taken)
+
+	commands := append([]config.Command(nil), cfg.Commands...)
+	for _, cmd := range fresh {
+		if err := config.Validate(commands, cmd, -1); err != nil {
+			return fmt.Errorf("%s: %w", cmd.Name, err)
+		}
+		commands = append(commands, cmd)
+	}
+	if err := cfg.Save(commands); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d commands (%d already present)\n", len(fresh), len(parsed)-len(fresh))
+	return nil
+}
+
+func runExport(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: tb export <navi | pet> [--category <category>] [--output <file>]")
+	}
+	format, ok := snippetFormats[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown export format %q (supported: navi, pet)", args[0])
+	}
+
+	fs := newFlagSet("export "+args[0], "export "+args[0]+" [--category <category>] [--output <file>]")
+	category := fs.String("category", "", "only export commands in this category or nested below it")
+	output := fs.String("output", "", "write to this new file instead of stdout")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	var commands []config.Command
+	for _, cmd := range cfg.Commands {
+		if *category == "" || config.InCategory(cmd.Category, *category) {
+			// Other tools have no workflows; they get the joined command.
+			cmd, _ = config.Resolve(cfg.Commands, cmd)
+			commands = append(commands, cmd)
+		}
+	}
+
+	if *output == "" {
+		return format.write(os.Stdout, commands)
+	}
+	var b bytes.Buffer
+	if err := format.write(&b, commands); err != nil {
+		return err
+	}
+	if err := fsutil.WriteNewFile(*output, b.Bytes(), 0644); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists; remove it or choose another --output", *output)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d commands to %s\n", len(commands), *output)
+	return nil
+}
+
+func importHistory(cfg *config.Config, args []string) error {
+	fs := newFlagSet("import history", "import history [--limit <n>]")
+	limit := fs.Int("limit", 200, "offer at most this many of the most frequent commands")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	entries, err := importer.ShellHistory(home)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	taken := make(map[string]bool)
+	for _, cmd := range cfg.Commands {
+		known[cmd.Command] = true
+		taken[cmd.Name] = true
+	}
+
+	var candidates []config.Command
+	var items []ui.PickItem
+	for _, e := range entries {
+		if len(candidates) == *limit {
+			break
+		}
+		if known[e.Command] || e.Command == "tb" || strings.HasPrefix(e.Command, "tb ") {
+			continue
+		}
+		cmd := config.Command{
+			Name:     importer.SuggestName(e.Command, taken),
+			Command:  e.Command,
+			Category: importCategory,
+		}
+		candidates = append(candidates, cmd)
+		items = append(items, ui.PickItem{Title: cmd.Name, Detail: cmd.Command, Note: fmt.Sprintf("%d×", e.Count)})
+	}
+	if len(candidates) == 0 {
+		fmt.Fprintln(os.Stderr, "No new commands found in shell history")
+		return nil
+	}
+
+	chosen, err := runPicker("Import from shell history", items, cfg.Keybindings)
+	if err != nil {
+		return err
+	}
+	if len(chosen) == 0 {
+		return nil
+	}
+
+	commands := append([]config.Command(nil), cfg.Commands...)
+	for _, i := range chosen {
+		commands = append(commands, candidates[i])
+	}
+	if err := cfg.Save(commands); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d commands into category %q\n", len(chosen), importCategory)
+	return nil
+}
+
+// runPicker shows the multi-select picker on stderr and returns the chosen
+// item indices, or nil if the user cancelled.
+func runPicker(title string, items []ui.PickItem, kb config.Keybindings) ([]int, error) {
+	lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(os.Stderr))
+	p := tea.NewProgram(ui.NewPicker(title, items, kb), tea.WithAltScreen(), tea.WithOutput(os.Stderr))
+	final, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+	return final.(ui.Picker).Chosen(), nil
+}
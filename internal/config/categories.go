@@ -0,0 +1,277 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"tb/internal/fsutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CategorySep separates the levels of a nested category such as "k8s/debug".
+const CategorySep = "/"
+
+// CategoryInfo customizes the tab of one category. Categories without an
+// entry get a plain tab after the listed ones, in alphabetical order.
+type CategoryInfo struct {
+	Name  string `yaml:"name"`
+	Title string `yaml:"title,omitempty"` // shown on the tab instead of Name
+	Icon  string `yaml:"icon,omitempty"`
+	// Hidden drops the tab; its commands still show under All and in search.
+	Hidden bool `yaml:"hidden,omitempty"`
+}
+
+// categoryInfo returns the entry for name, if the config has one.
+func (c *Config) categoryInfo(name string) (CategoryInfo, bool) {
+	i := slices.IndexFunc(c.CategoryInfo, func(info CategoryInfo) bool { return info.Name == name })
+	if i < 0 {
+		return CategoryInfo{}, false
+	}
+	return c.CategoryInfo[i], true
+}
+
+// InCategory reports whether cat is parent or nested anywhere below it.
+func InCategory(cat, parent string) bool {
+	return cat == parent || strings.HasPrefix(cat, parent+CategorySep)
+}
+
+// RootCategory returns the top level of cat, e.g. "k8s" for "k8s/debug".
+func RootCategory(cat string) string {
+	root, _, _ := strings.Cut(cat, CategorySep)
+	return root
+}
+
+// WithParents returns cats plus every parent of a nested category, so
+// "k8s/deploy/canary" brings in "k8s" and "k8s/deploy".
+func WithParents(cats []string) []string {
+	all := slices.Clone(cats)
+	for _, cat := range cats {
+		for i := strings.LastIndex(cat, CategorySep); i > 0; i = strings.LastIndex(cat[:i], CategorySep) {
+			if parent := cat[:i]; !slices.Contains(all, parent) {
+				all = append(all, parent)
+			}
+		}
+	}
+	return all
+}
+
+// OrderCategories arranges cats for the tab row. Nested categories follow
+// their parent, and siblings are ordered the same way at every level:
+// those listed under categories: first, in the order given, then the rest
+// alphabetically. Hidden categories are dropped.
+func (c *Config) OrderCategories(cats []string) []string {
+	rank := make(map[string]int, len(c.CategoryInfo))
+	for i, info := range c.CategoryInfo {
+		if _, ok := rank[info.Name]; !ok {
+			rank[info.Name] = i
+		}
+	}
+	ordered := make([]string, 0, len(cats))
+	for _, cat := range cats {
+		if info, ok := c.categoryInfo(cat); !ok || !info.Hidden {
+			ordered = append(ordered, cat)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a := strings.Split(ordered[i], CategorySep)
+		b := strings.Split(ordered[j], CategorySep)
+		for n := 1; n <= min(len(a), len(b)); n++ {
+			pa := strings.Join(a[:n], CategorySep)
+			pb := strings.Join(b[:n], CategorySep)
+			if pa == pb {
+				continue
+			}
+			ra, aok := rank[pa]
+			rb, bok := rank[pb]
+			switch {
+			case aok && bok:
+				return ra < rb
+			case aok != bok:
+				return aok
+			}
+			return a[n-1] < b[n-1]
+		}
+		return len(a) < len(b)
+	})
+	return ordered
+}
+
+// CategoryLabel returns the text shown for a category: its title, or its
+// name, after its icon.
+func (c *Config) CategoryLabel(name string) string {
+	return c.SubCategoryLabel(name, "")
+}
+
+// SubCategoryLabel is CategoryLabel for a category shown under parent,
+// which is left out of the name, e.g. "debug" for "k8s/debug" under "k8s".
+func (c *Config) SubCategoryLabel(name, parent string) string {
+	info, _ := c.categoryInfo(name)
+	label := name
+	if parent != "" {
+		label = strings.TrimPrefix(name, parent+CategorySep)
+	}
+	if info.Title != "" {
+		label = info.Title
+	}
+	if info.Icon != "" {
+		label = info.Icon + " " + label
+	}
+	return label
+}
+
+// RenameCategoryInfo renames the categories: entries of from and of the
+// categories nested below it to sit under to instead, in the global config,
+// so a renamed category keeps its tab settings. Entries whose new name
+// already has one are left alone. It reports whether anything was renamed.
+func (c *Config) RenameCategoryInfo(from, to string) (bool, error) {
+	moves := make(map[string]string)
+	for _, info := range c.CategoryInfo {
+		if !InCategory(info.Name, from) {
+			continue
+		}
+		name := to + strings.TrimPrefix(info.Name, from)
+		if _, ok := c.categoryInfo(name); !ok {
+			moves[info.Name] = name
+		}
+	}
+	if len(moves) == 0 {
+		return false, nil
+	}
+
+	renamed := false
+	err := withLock(c.path, func() error {
+		target := realPath(c.path)
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return err
+		}
+		out, changed := data, false
+		for old, name := range moves {
+			var ok bool
+			if out, ok, err = renameCategoryEntry(out, old, name); err != nil {
+				return err
+			}
+			changed = changed || ok
+		}
+		if !changed {
+			return nil
+		}
+		perm := fileMode(target)
+		if err := backup(c.path, data, perm); err != nil {
+			return err
+		}
+		if err := fsutil.WriteFileAtomic(target, out, perm); err != nil {
+			return err
+		}
+		renamed = true
+		return nil
+	})
+	if err != nil || !renamed {
+		return false, err
+	}
+	c.stamps[c.path] = stampOf(c.path)
+	for i, info := range c.CategoryInfo {
+		if name, ok := moves[info.Name]; ok {
+			c.CategoryInfo[i].Name = name
+		}
+	}
+	return true, nil
+}
+
+// renameCategoryEntry rewrites the name scalar of from's entry in data,
+// leaving every other byte alone.
+func renameCategoryEntry(data []byte, from, to string) ([]byte, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, false, nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "categories" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, item := range root.Content[i+1].Content {
+			for j := 0; item.Kind == yaml.MappingNode && j+1 < len(item.Content); j += 2 {
+				if v := item.Content[j+1]; item.Content[j].Value == "name" && v.Value == from {
+					return replaceScalar(data, v, to)
+				}
+			}
+		}
+	}
+	return data, false, nil
+}
+
+// replaceScalar swaps the single-line scalar n in data for value.
+func replaceScalar(data []byte, n *yaml.Node, value string) ([]byte, bool, error) {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if n.Line < 1 || n.Line > len(lines) {
+		return data, false, nil
+	}
+	line := lines[n.Line-1]
+	// Columns count characters, not bytes.
+	runes := []rune(string(line))
+	if n.Column < 1 || n.Column > len(runes) {
+		return data, false, nil
+	}
+	start := len(string(runes[:n.Column-1]))
+	end := scalarEnd(line, start, n)
+	if end < 0 {
+		return data, false, nil
+	}
+	enc, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, false, err
+	}
+	var out bytes.Buffer
+	for _, l := range lines[:n.Line-1] {
+		out.Write(l)
+	}
+	out.Write(line[:start])
+	out.WriteString(strings.TrimSuffix(string(enc), "\n"))
+	out.Write(line[end:])
+	for _, l := range lines[n.Line:] {
+		out.Write(l)
+	}
+	return out.Bytes(), true, nil
+}
+
+// scalarEnd returns the offset in line just past the scalar n starting at
+// start, or -1 if it can't be located on this line.
+func scalarEnd(line []byte, start int, n *yaml.Node) int {
+	if start < 0 || start >= len(line) {
+		return -1
+	}
+	switch n.Style {
+	case yaml.DoubleQuotedStyle:
+		for i := start + 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	case 0:
+		if end := start + len(n.Value); bytes.HasPrefix(line[start:], []byte(n.Value)) {
+			return end
+		}
+	}
+	return -1
+}
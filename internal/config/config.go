@@ -1,88 +1,369 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"sort"
+	"strings"
+
+	"tb/internal/fsutil"
+	"tb/internal/strdist"
 
 	"gopkg.in/yaml.v3"
 )
 
-const configFileName = ".tb.yaml"
+const (
+	configFileName    = ".tb.yaml"
+	xdgConfigFileName = "config.yaml"
+)
 
 type Keybindings struct {
-	Up       []string `yaml:"up,omitempty"`
-	Down     []string `yaml:"down,omitempty"`
-	NextTab  []string `yaml:"next_tab,omitempty"`
-	PrevTab  []string `yaml:"prev_tab,omitempty"`
-	Search   []string `yaml:"search,omitempty"`
-	ClearEsc []string `yaml:"clear_esc,omitempty"`
-	Select   []string `yaml:"select,omitempty"`
-	Copy     []string `yaml:"copy,omitempty"`
-	Quit     []string `yaml:"quit,omitempty"`
-	Create   []string `yaml:"create,omitempty"`
-	Edit     []string `yaml:"edit,omitempty"`
-	Delete   []string `yaml:"delete,omitempty"`
+	Up             []string `yaml:"up,omitempty"`
+	Down           []string `yaml:"down,omitempty"`
+	NextTab        []string `yaml:"next_tab,omitempty"`
+	PrevTab        []string `yaml:"prev_tab,omitempty"`
+	NextSubTab     []string `yaml:"next_subtab,omitempty"`
+	PrevSubTab     []string `yaml:"prev_subtab,omitempty"`
+	Search         []string `yaml:"search,omitempty"`
+	ClearEsc       []string `yaml:"clear_esc,omitempty"`
+	Select         []string `yaml:"select,omitempty"`
+	Copy           []string `yaml:"copy,omitempty"`
+	Quit           []string `yaml:"quit,omitempty"`
+	Create         []string `yaml:"create,omitempty"`
+	Edit           []string `yaml:"edit,omitempty"`
+	EditExternal   []string `yaml:"edit_external,omitempty"`
+	Delete         []string `yaml:"delete,omitempty"`
+	Pin            []string `yaml:"pin,omitempty"`
+	RenameCategory []string `yaml:"rename_category,omitempty"`
+	Mark           []string `yaml:"mark,omitempty"`
+	MarkAll        []string `yaml:"mark_all,omitempty"`
+	Move           []string `yaml:"move,omitempty"`
+	Tag            []string `yaml:"tag,omitempty"`
+	Export         []string `yaml:"export,omitempty"`
+	Undo           []string `yaml:"undo,omitempty"`
+	Redo           []string `yaml:"redo,omitempty"`
 }
 
 type Config struct {
 	Commands    []Command   `yaml:"commands"`
 	Keybindings Keybindings `yaml:"keybindings,omitempty"`
+	// Include lists glob patterns of extra command files, relative to the config file.
+	Include []string `yaml:"include,omitempty"`
+	// TrashDays is how long deleted commands stay in the trash: 0 means the
+	// default of 30 days, negative means forever.
+	TrashDays int `yaml:"trash_days,omitempty"`
+	// CategoryInfo sets the order, labels and visibility of category tabs.
+	CategoryInfo []CategoryInfo `yaml:"categories,omitempty"`
+
+	path        string   // global config file
+	projectPath string   // project-local .tb.yaml, empty if none was found
+	files       []string // every file commands were loaded from, global first
+
+	explicitPath string               // the --config path Load was given, for Reload
+	stamps       map[string]fileStamp // version of each file as last read or written
 }
 
 type Command struct {
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	Command     string `yaml:"command"`
-	Category    string `yaml:"category"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Command     string `yaml:"command" json:"command"`
+	// Steps makes the command a workflow that runs the named commands in
+	// order instead of a command of its own.
+	Steps        []string               `yaml:"steps,omitempty" json:"steps,omitempty"`
+	Category     string                 `yaml:"category" json:"category"`
+	Tags         []string               `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Placeholders map[string]Placeholder `yaml:"placeholders,omitempty" json:"placeholders,omitempty"`
+	Pinned       bool                   `yaml:"pinned,omitempty" json:"pinned,omitempty"`
+
+	// Origin is the file the command was loaded from. Empty means the global config.
+	Origin string `yaml:"-" json:"origin,omitempty"`
 }
 
-// Load reads ~/.tb.yaml. Creates a default file if it doesn't exist.
-func Load() (*Config, error) {
-	home, err := os.UserHomeDir()
+// Load reads the global config file, creating a default one if it doesn't
+// exist, then merges in the commands of included files, the commands.d
+// directory and the nearest project-local .tb.yaml.
+// explicitPath (from --config) takes precedence; see ResolvePath.
+func Load(explicitPath string) (*Config, error) {
+	return load(explicitPath, true)
+}
+
+func load(explicitPath string, create bool) (*Config, error) {
+	path, err := resolveAbs(explicitPath)
 	if err != nil {
 		return nil, err
 	}
-	path := filepath.Join(home, configFileName)
 
-	data, err := os.ReadFile(path)
+	// Stamp before reading, so a write racing the read shows up as a change.
+	stamp := stampOf(path)
+	cfg, err := readFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return createDefault(path)
+		if !create || !os.IsNotExist(err) {
+			return nil, err
 		}
+		if cfg, err = createDefault(path); err != nil {
+			return nil, err
+		}
+		stamp = stampOf(path)
+	}
+	cfg.path = path
+	cfg.files = []string{path}
+	cfg.explicitPath = explicitPath
+	cfg.stamps = map[string]fileStamp{path: stamp}
+
+	extra, err := cfg.extraFiles()
+	if err != nil {
 		return nil, err
 	}
+	for _, p := range extra {
+		cfg.stamps[p] = stampOf(p)
+		pack, err := readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		cfg.files = append(cfg.files, p)
+		cfg.Commands = append(cfg.Commands, pack.Commands...)
+	}
+	return cfg, nil
+}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+// Files returns every config file tb reads, global config first. The global
+// file is only parsed for its include patterns, so Files works even when
+// errors elsewhere in it would make Load fail.
+func Files(explicitPath string) ([]string, error) {
+	path, err := resolveAbs(explicitPath)
+	if err != nil {
 		return nil, err
 	}
-	return &cfg, nil
+	cfg := &Config{path: path}
+	if data, err := os.ReadFile(path); err == nil {
+		var partial struct {
+			Include []string `yaml:"include"`
+		}
+		_ = yaml.Unmarshal(data, &partial)
+		cfg.Include = partial.Include
+	}
+
+	extra, err := cfg.extraFiles()
+	if err != nil {
+		// A bad include pattern is itself something to report; the global
+		// file still gets checked.
+		return []string{path}, nil
+	}
+	return append([]string{path}, extra...), nil
 }
 
-// Save writes the command list to ~/.tb.yaml, preserving non-command fields (e.g., keybindings).
-func Save(commands []Command) error {
+// resolveAbs is ResolvePath made absolute, so origins compare reliably.
+func resolveAbs(explicitPath string) (string, error) {
+	path, err := ResolvePath(explicitPath)
+	if err != nil {
+		return "", err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path, nil
+}
+
+// extraFiles returns the included files followed by the project-local
+// .tb.yaml, recording the latter as the config's project path.
+func (c *Config) extraFiles() ([]string, error) {
+	home, _ := os.UserHomeDir()
+	extra, err := c.includedFiles(home)
+	if err != nil {
+		return nil, err
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if p := findProjectFile(cwd, home); p != "" && !slices.Contains(extra, p) && p != c.path {
+			c.projectPath = p
+			extra = append(extra, p)
+		}
+	}
+	return extra, nil
+}
+
+// includedFiles expands the include patterns and the commands.d directory
+// into a de-duplicated list of files, excluding the global config itself
+// and the backups, locks and temp files tb keeps next to each file.
+func (c *Config) includedFiles(home string) ([]string, error) {
+	patterns := []string{filepath.Join(configDir(home), "commands.d", "*.yaml")}
+	for _, pattern := range c.Include {
+		if strings.HasPrefix(pattern, "~/") {
+			pattern = filepath.Join(home, pattern[2:])
+		} else if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(c.path), pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	seen := map[string]struct{}{c.path: {}}
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if _, ok := seen[match]; ok || isSidecar(match) || !fileExists(match) {
+				continue
+			}
+			seen[match] = struct{}{}
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// ResolvePath returns the global config file to use, in order of precedence:
+//
+//  1. explicitPath, from the --config flag
+//  2. $TB_CONFIG
+//  3. $XDG_CONFIG_HOME/tb/config.yaml (default ~/.config/tb/config.yaml), if it exists
+//  4. ~/.tb.yaml, if it exists
+//
+// If none of the files exist, the XDG path is used when $XDG_CONFIG_HOME is
+// set and ~/.tb.yaml otherwise.
+func ResolvePath(explicitPath string) (string, error) {
+	if explicitPath != "" {
+		return explicitPath, nil
+	}
+	if p := os.Getenv("TB_CONFIG"); p != "" {
+		return p, nil
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		return "", err
+	}
+	xdgPath := filepath.Join(configDir(home), xdgConfigFileName)
+	if fileExists(xdgPath) {
+		return xdgPath, nil
 	}
-	path := filepath.Join(home, configFileName)
+	legacyPath := filepath.Join(home, configFileName)
+	if fileExists(legacyPath) || os.Getenv("XDG_CONFIG_HOME") == "" {
+		return legacyPath, nil
+	}
+	return xdgPath, nil
+}
 
-	// Read existing config to preserve non-command fields (e.g., keybindings)
-	var cfg Config
-	if data, err := os.ReadFile(path); err == nil {
-		_ = yaml.Unmarshal(data, &cfg)
+// configDir returns tb's directory under $XDG_CONFIG_HOME, defaulting to ~/.config/tb.
+func configDir(home string) string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		base = filepath.Join(home, ".config")
 	}
-	cfg.Commands = commands
+	return filepath.Join(base, "tb")
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
 
-	data, err := yaml.Marshal(&cfg)
+// readFile parses a single config file and stamps each command with its origin.
+func readFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range cfg.Commands {
+		cfg.Commands[i].Origin = path
+	}
+	return &cfg, nil
+}
+
+// findProjectFile walks up from dir looking for a .tb.yaml. The walk stops at
+// the git root (a directory containing .git) or on reaching home, so the
+// global config is never mistaken for a project file.
+func findProjectFile(dir, home string) string {
+	for dir != home {
+		p := filepath.Join(dir, configFileName)
+		if fileExists(p) {
+			return p
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+	return ""
+}
+
+// ProjectPath returns the project-local config file, or "" if none was found.
+func (c *Config) ProjectPath() string {
+	return c.projectPath
+}
+
+// Save writes commands back to the files they came from, preserving
+// non-command fields (e.g., keybindings). Commands without an origin, or
+// whose origin isn't one of the loaded files, go to the global config.
+// Files whose commands are unchanged are left alone. If a command this save
+// changes was also changed on disk since it was loaded, Save returns a
+// *ConflictError and leaves that file untouched; files saved before the
+// error keep their new commands in c.Commands, the rest their old ones.
+// Nothing is written if the change would leave a workflow with a missing
+// step.
+func (c *Config) Save(commands []Command) error {
+	if err := CheckSteps(c.Commands, commands); err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	before := c.byFile(c.Commands)
+	after := c.byFile(commands)
+	saved := make(map[string]bool)
+	for _, path := range c.files {
+		if reflect.DeepEqual(before[path], after[path]) {
+			continue
+		}
+		merged, err := writeCommands(path, before[path], after[path])
+		if err != nil {
+			// Match what is on disk now, so the next save starts from it.
+			c.Commands = nil
+			for _, p := range c.files {
+				if saved[p] {
+					c.Commands = append(c.Commands, after[p]...)
+				} else {
+					c.Commands = append(c.Commands, before[p]...)
+				}
+			}
+			return err
+		}
+		saved[path] = true
+		// Our own write isn't an external change, unless it merged one in.
+		if !merged {
+			c.stamps[path] = stampOf(path)
+		}
+	}
+	c.Commands = slices.Clone(commands)
+	return nil
 }
 
-// Categories returns sorted unique category names from the command list.
+// byFile groups commands by the file they belong to. Commands without an
+// origin, or from a file that isn't loaded, belong to the global config.
+func (c *Config) byFile(commands []Command) map[string][]Command {
+	groups := make(map[string][]Command)
+	for _, cmd := range commands {
+		path := cmd.Origin
+		if path == "" || !slices.Contains(c.files, path) {
+			path = c.path
+		}
+		groups[path] = append(groups[path], cmd)
+	}
+	return groups
+}
+
+// Categories returns sorted unique category names from the command list,
+// including the parents of nested categories.
 func (c *Config) Categories() []string {
 	seen := make(map[string]struct{})
 	for _, cmd := range c.Commands {
@@ -94,10 +375,83 @@ func (c *Config) Categories() []string {
 	for cat := range seen {
 		cats = append(cats, cat)
 	}
+	cats = WithParents(cats)
 	sort.Strings(cats)
 	return cats
 }
 
+// SimilarCategory returns the existing category that cat looks like a
+// variant or typo of, such as "Docker" or "dokcer" for "docker", or "" if
+// cat is already in cats or close to none of them.
+func SimilarCategory(cats []string, cat string) string {
+	if cat == "" || slices.Contains(cats, cat) {
+		return ""
+	}
+	// Allow one typo in short names and two in longer ones.
+	limit := 1
+	if len([]rune(cat)) > 5 {
+		limit = 2
+	}
+	best, bestDist := "", limit+1
+	for _, c := range cats {
+		if d := strdist.Distance(strings.ToLower(cat), strings.ToLower(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// Validate checks cmd the way the TUI form does: name and command are
+// required, and the name must be unique. A workflow has steps instead of a
+// command; they are checked against the whole list by Save. skip is the
+// index of the command being edited in commands, or -1 for a new command.
+func Validate(commands []Command, cmd Command, skip int) error {
+	if strings.TrimSpace(cmd.Name) == "" {
+		return errors.New("name is required")
+	}
+	hasCommand := strings.TrimSpace(cmd.Command) != ""
+	switch {
+	case !hasCommand && !cmd.IsWorkflow():
+		return errors.New("command is required")
+	case hasCommand && cmd.IsWorkflow():
+		return errors.New("a workflow runs its steps; leave command empty")
+	}
+	for i, c := range commands {
+		if c.Name == cmd.Name && i != skip {
+			return fmt.Errorf("name %q already exists", cmd.Name)
+		}
+	}
+	return nil
+}
+
+// Find returns the index of the command with the given name, or -1.
+func Find(commands []Command, name string) int {
+	return slices.IndexFunc(commands, func(c Command) bool { return c.Name == name })
+}
+
+// ParseTags splits a comma or space separated tag list, dropping a leading
+// '#' and duplicates.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		tag := strings.TrimPrefix(field, "#")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasTag reports whether the command carries tag, ignoring case.
+func (c Command) HasTag(tag string) bool {
+	for _, t := range c.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
 func createDefault(path string) (*Config, error) {
 	cfg := &Config{
 		Commands: []Command{
@@ -110,8 +464,14 @@ func createDefault(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return nil, err
 	}
+	if err := fsutil.WriteFileAtomic(path, data, 0644); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Commands {
+		cfg.Commands[i].Origin = path
+	}
 	return cfg, nil
 }
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupHome gives the test its own home, config directory and project
+// directory, and returns the global config path.
+func setupHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("TB_CONFIG", "")
+	project := filepath.Join(home, "project")
+	for _, dir := range []string{filepath.Join(home, ".config", "tb", "commands.d"), filepath.Join(project, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(project)
+	return filepath.Join(home, ".config", "tb", "config.yaml")
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveKeepsFilesSavedBeforeAnError(t *testing.T) {
+	path := setupHome(t)
+	pack := filepath.Join(filepath.Dir(path), "commands.d", "git.yaml")
+	writeFile(t, path, "commands:\n  - name: a\n    command: one\n")
+	writeFile(t, pack, "commands:\n  - name: b\n    command: two\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, pack, "commands:\n  - name: b\n    command: zwei\n")
+	commands := []Command{cfg.Commands[0], cfg.Commands[1]}
+	commands[0].Command, commands[1].Command = "uno", "dos"
+	var conflict *ConflictError
+	if err := cfg.Save(commands); !errors.As(err, &conflict) {
+		t.Fatalf("got %v, want a *ConflictError", err)
+	}
+	if got := []string{cfg.Commands[0].Command, cfg.Commands[1].Command}; got[0] != "uno" || got[1] != "two" {
+		t.Errorf("commands after a failed save are %q, want the saved uno and the old two", got)
+	}
+}
+
+func TestChangedSeesNewFiles(t *testing.T) {
+	path := setupHome(t)
+	writeFile(t, path, "commands:\n  - name: a\n    command: one\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Changed() {
+		t.Fatal("Changed right after Load")
+	}
+
+	for _, file := range []string{
+		filepath.Join(filepath.Dir(path), "commands.d", "git.yaml"),
+		filepath.Join(os.Getenv("HOME"), "project", ".tb.yaml"),
+	} {
+		writeFile(t, file, "commands:\n  - name: "+filepath.Base(file)+"\n    command: x\n")
+		if !cfg.Changed() {
+			t.Errorf("adding %s: not Changed", file)
+		}
+		if err := cfg.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		if Find(cfg.Commands, filepath.Base(file)) < 0 {
+			t.Errorf("adding %s: its command wasn't loaded", file)
+		}
+		if cfg.Changed() {
+			t.Errorf("adding %s: still Changed after Reload", file)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commandFields are the yaml keys of Command. Keys outside this set are left
+// alone when an entry is rewritten.
+var commandFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeFor[Command]()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// spliceCommands returns data with its command list replaced by commands.
+// Entries that didn't change are copied byte for byte, changed entries are
+// re-encoded in place, so comments, key order and formatting elsewhere in
+// the file survive.
+func spliceCommands(data []byte, commands []Command) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(doc.Content) == 0 {
+		// Empty or comments only.
+		return appendCommandsKey(lines, commands)
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("top level is not a mapping")
+	}
+
+	var key, seq *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "commands" {
+			key, seq = root.Content[i], root.Content[i+1]
+		}
+	}
+	switch {
+	case key == nil:
+		return appendCommandsKey(lines, commands)
+	case seq.Kind == yaml.SequenceNode && seq.Style&yaml.FlowStyle == 0 && len(seq.Content) > 0:
+		return spliceItems(lines, seq, max(2, seq.Column-key.Column), commands)
+	default:
+		// null, [] or a flow list: replace the whole value with a block list.
+		return replaceValue(lines, key, commands)
+	}
+}
+
+// EncodePack returns commands as a standalone command file, in the same
+// layout tb uses when it saves, for including elsewhere or sharing.
+func EncodePack(commands []Command) ([]byte, error) {
+	return spliceCommands(nil, commands)
+}
+
+// spliceItems rewrites a non-empty block sequence entry by entry.
+func spliceItems(lines []string, seq *yaml.Node, indent int, commands []Command) ([]byte, error) {
+	dash := seq.Column - 1 // every "- " of a block sequence is in the same column
+	n := len(seq.Content)
+
+	// Each entry spans [start, end): its dash line up to the next entry,
+	// minus trailing blank lines and comments at the dash column, which
+	// belong to whatever follows. Those gaps are kept as the next entry's lead.
+	starts := make([]int, n)
+	for i, item := range seq.Content {
+		l := item.Line - 1
+		for l > 0 && !isDashLine(lines[l], dash) {
+			l--
+		}
+		starts[i] = l
+	}
+	ends := make([]int, n)
+	for i := range n {
+		next := len(lines)
+		if i+1 < n {
+			next = starts[i+1]
+		} else {
+			for l := starts[i] + 1; l < len(lines); l++ {
+				if !isGap(lines[l], dash) && (indentOf(lines[l]) < dash || !isDashLine(lines[l], dash) && indentOf(lines[l]) == dash) {
+					next = l
+					break
+				}
+			}
+		}
+		end := next
+		for end > starts[i]+1 && isGap(lines[end-1], dash) {
+			end--
+		}
+		ends[i] = end
+	}
+	// An entry's lead is the gap above it. The first entry's starts after
+	// the "commands:" line, so its head comment goes when it is deleted.
+	leads := make([]int, n)
+	leads[0] = starts[0]
+	for leads[0] > 0 && isGap(lines[leads[0]-1], dash) {
+		leads[0]--
+	}
+	for i := 1; i < n; i++ {
+		leads[i] = ends[i-1]
+	}
+
+	disk := make([]Command, n)
+	for i, item := range seq.Content {
+		if err := item.Decode(&disk[i]); err != nil {
+			return nil, err
+		}
+	}
+	match := matchEntries(disk, commands)
+
+	// New entries copy the file's spacing: a blank line between entries or none.
+	sep := ""
+	if n > 1 && slices.ContainsFunc(lines[leads[1]:starts[1]], func(l string) bool { return strings.TrimSpace(l) == "" }) {
+		sep = "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(lines[:leads[0]], ""))
+	pad := strings.Repeat(" ", dash)
+	for j, cmd := range commands {
+		i := match[j]
+		if i < 0 {
+			if j > 0 {
+				b.WriteString(sep)
+			}
+			entry, err := encodeEntry(nil, cmd, pad, indent)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(entry)
+			continue
+		}
+		from := leads[i]
+		if j == 0 && i > 0 {
+			// Don't leave a gap under "commands:" when the entries above were deleted.
+			for from < starts[i] && strings.TrimSpace(lines[from]) == "" {
+				from++
+			}
+		}
+		b.WriteString(strings.Join(lines[from:starts[i]], ""))
+		cmd.Origin = ""
+		if reflect.DeepEqual(disk[i], cmd) {
+			b.WriteString(strings.Join(lines[starts[i]:ends[i]], ""))
+			continue
+		}
+		entry, err := encodeEntry(seq.Content[i], cmd, pad, indent)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(entry)
+	}
+	b.WriteString(strings.Join(lines[ends[n-1]:], ""))
+	return []byte(b.String()), nil
+}
+
+// matchEntries pairs each command with the file entry it came from, by name
+// first and then in order, so a renamed command keeps its place and comments.
+// Commands without an entry get -1.
+func matchEntries(disk, commands []Command) []int {
+	used := make([]bool, len(disk))
+	match := make([]int, len(commands))
+	for j, cmd := range commands {
+		match[j] = -1
+		for i, d := range disk {
+			if !used[i] && d.Name == cmd.Name {
+				match[j], used[i] = i, true
+				break
+			}
+		}
+	}
+	var free []int
+	for i := range disk {
+		if !used[i] {
+			free = append(free, i)
+		}
+	}
+	for j := range commands {
+		if match[j] < 0 && len(free) > 0 {
+			match[j], free = free[0], free[1:]
+		}
+	}
+	return match
+}
+
+// rebase applies the edits that turned before into after to disk, the
+// commands currently in the file, so a save keeps what another tb session
+// or an editor wrote in the meantime. It returns the names of commands
+// changed both here and on disk; the result is only usable if there are none.
+func rebase(disk, before, after []Command) ([]Command, []string) {
+	match := matchEntries(before, after)
+	// Where each command this session started from ended up: an index
+	// into after, or -1 if it was deleted.
+	dest := make(map[string]int, len(before))
+	base := make(map[string]Command, len(before))
+	for _, cmd := range before {
+		dest[cmd.Name] = -1
+		base[cmd.Name] = cmd
+	}
+	for j, i := range match {
+		if i >= 0 {
+			dest[before[i].Name] = j
+		}
+	}
+	changed := func(j int) bool {
+		return !sameCommand(before[match[j]], after[j])
+	}
+
+	var out []Command
+	var conflicts []string
+	placed := make([]bool, len(after))
+	for _, cmd := range disk {
+		j, known := dest[cmd.Name]
+		switch {
+		case !known:
+			out = append(out, cmd) // added elsewhere
+		case j >= 0 && placed[j]:
+			// a duplicate name
+		case j < 0 || changed(j):
+			// Deleted or edited here: fine unless it was edited there too.
+			if !sameCommand(cmd, base[cmd.Name]) {
+				conflicts = append(conflicts, cmd.Name)
+			}
+			if j >= 0 {
+				out = append(out, after[j])
+				placed[j] = true
+			}
+		default:
+			out = append(out, cmd)
+			placed[j] = true
+		}
+	}
+	for j, cmd := range after {
+		switch {
+		case placed[j]:
+		case match[j] < 0:
+			// New here: put it after the command it follows in after,
+			// so e.g. undoing a delete restores the entry in place.
+			at := 0
+			if j > 0 {
+				at = slices.IndexFunc(out, func(c Command) bool { return c.Name == after[j-1].Name }) + 1
+				if at == 0 {
+					at = len(out)
+				}
+			}
+			out = slices.Insert(out, at, cmd)
+		case changed(j):
+			conflicts = append(conflicts, before[match[j]].Name) // edited here, deleted there
+		}
+	}
+	return out, conflicts
+}
+
+// sameCommand compares commands, ignoring which file they were loaded from.
+func sameCommand(a, b Command) bool {
+	a.Origin, b.Origin = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+func sameCommands(a, b []Command) bool {
+	return slices.EqualFunc(a, b, sameCommand)
+}
+
+// replaceValue replaces the value of the commands key, and any lines it
+// spans, with a block list.
+func replaceValue(lines []string, key *yaml.Node, commands []Command) ([]byte, error) {
+	keyLine := key.Line - 1
+	keyIndent := key.Column - 1
+	line := lines[keyLine]
+	colon := strings.IndexByte(line[keyIndent+len(key.Value):], ':')
+	if colon < 0 {
+		return nil, fmt.Errorf("line %d: cannot find the commands value", key.Line)
+	}
+	head := line[:keyIndent+len(key.Value)+colon+1]
+
+	end := len(lines)
+	for l := keyLine + 1; l < len(lines); l++ {
+		if !isGap(lines[l], keyIndent) && indentOf(lines[l]) <= keyIndent {
+			end = l
+			break
+		}
+	}
+	for end > keyLine+1 && isGap(lines[end-1], keyIndent) {
+		end--
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(lines[:keyLine], ""))
+	b.WriteString(head)
+	if err := writeEntries(&b, commands, strings.Repeat(" ", keyIndent+2)); err != nil {
+		return nil, err
+	}
+	b.WriteString(strings.Join(lines[end:], ""))
+	return []byte(b.String()), nil
+}
+
+// appendCommandsKey adds a commands key to the end of a file that has none.
+func appendCommandsKey(lines []string, commands []Command) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString(strings.Join(lines, ""))
+	if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("commands:")
+	if err := writeEntries(&b, commands, "  "); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// writeEntries finishes a "commands:" line with a block list at pad.
+func writeEntries(b *strings.Builder, commands []Command, pad string) error {
+	if len(commands) == 0 {
+		b.WriteString(" []\n")
+		return nil
+	}
+	b.WriteString("\n")
+	for _, cmd := range commands {
+		entry, err := encodeEntry(nil, cmd, pad, 2)
+		if err != nil {
+			return err
+		}
+		b.WriteString(entry)
+	}
+	return nil
+}
+
+// encodeEntry renders cmd as a sequence entry whose dash is at pad. If old
+// is the entry's existing mapping, its key order, unknown keys and comments
+// are kept, and only the values that changed are replaced.
+func encodeEntry(old *yaml.Node, cmd Command, pad string, indent int) (string, error) {
+	cmd.Origin = ""
+	var fresh yaml.Node
+	if err := fresh.Encode(cmd); err != nil {
+		return "", err
+	}
+	item := &fresh
+	if old != nil {
+		item = mergeEntry(old, &fresh)
+	} else {
+		item = omitEmpty(&fresh)
+	}
+	// Comments above and below the entry are copied verbatim by the caller.
+	item.HeadComment, item.FootComment = "", ""
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(item); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	first := true
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		switch {
+		case line == "":
+		case strings.TrimSpace(line) == "":
+			b.WriteString(line)
+		case first && !strings.HasPrefix(line, "#"):
+			b.WriteString(pad + "- " + line)
+			first = false
+		default:
+			b.WriteString(pad + "  " + line)
+		}
+	}
+	return b.String(), nil
+}
+
+// mergeEntry updates the old entry mapping with the values from fresh.
+func mergeEntry(old, fresh *yaml.Node) *yaml.Node {
+	values := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(fresh.Content); i += 2 {
+		values[fresh.Content[i].Value] = fresh.Content[i+1]
+	}
+
+	merged := *old
+	merged.Content = nil
+	for i := 0; i+1 < len(old.Content); i += 2 {
+		k, v := old.Content[i], old.Content[i+1]
+		nv, ok := values[k.Value]
+		switch {
+		case ok:
+			delete(values, k.Value)
+			if !sameValue(v, nv) {
+				nv.HeadComment, nv.LineComment, nv.FootComment = v.HeadComment, v.LineComment, v.FootComment
+				if v.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 && strings.Contains(nv.Value, "\n") {
+					nv.Style = v.Style
+				}
+				if v.Kind == yaml.SequenceNode && nv.Kind == yaml.SequenceNode {
+					nv.Style |= v.Style & yaml.FlowStyle // keep [a, b] lists inline
+				}
+				v = nv
+			}
+		case commandFields[k.Value]:
+			continue // the field is now empty
+		}
+		merged.Content = append(merged.Content, k, v)
+	}
+	for i := 0; i+1 < len(fresh.Content); i += 2 {
+		k, v := fresh.Content[i], fresh.Content[i+1]
+		if _, ok := values[k.Value]; ok {
+			merged.Content = append(merged.Content, k, v)
+		}
+	}
+	return omitEmpty(&merged)
+}
+
+// omitEmpty drops the keys of empty optional strings, e.g. description: "".
+func omitEmpty(m *yaml.Node) *yaml.Node {
+	out := *m
+	out.Content = nil
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if v := m.Content[i+1]; v.Kind != yaml.ScalarNode || v.Value != "" {
+			out.Content = append(out.Content, m.Content[i], v)
+		}
+	}
+	return &out
+}
+
+// sameValue reports whether two nodes decode to the same data.
+func sameValue(a, b *yaml.Node) bool {
+	var va, vb any
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// isDashLine reports whether line holds a sequence dash at column col.
+func isDashLine(line string, col int) bool {
+	return indentOf(line) == col && strings.HasPrefix(line[col:], "-")
+}
+
+// isGap reports whether line is blank or a comment indented at most col.
+func isGap(line string, col int) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#") && indentOf(line) <= col
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,358 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func cmd(name, command string) Command {
+	return Command{Name: name, Command: command}
+}
+
+const commented = `# tb config
+commands:
+  # docker stuff
+  - name: up
+    command: docker compose up
+
+  # git stuff
+  - name: st
+    command: git status # short
+
+  - name: log
+    command: git log
+# the end
+`
+
+func TestSpliceCommands(t *testing.T) {
+	up, st, log := cmd("up", "docker compose up"), cmd("st", "git status"), cmd("log", "git log")
+	tests := []struct {
+		name     string
+		data     string
+		commands []Command
+		want     string
+	}{
+		{
+			name:     "unchanged",
+			data:     commented,
+			commands: []Command{up, st, log},
+			want:     commented,
+		},
+		{
+			name:     "delete first",
+			data:     commented,
+			commands: []Command{st, log},
+			want: `# tb config
+commands:
+  # git stuff
+  - name: st
+    command: git status # short
+
+  - name: log
+    command: git log
+# the end
+`,
+		},
+		{
+			name:     "delete middle",
+			data:     commented,
+			commands: []Command{up, log},
+			want: `# tb config
+commands:
+  # docker stuff
+  - name: up
+    command: docker compose up
+
+  - name: log
+    command: git log
+# the end
+`,
+		},
+		{
+			name:     "delete last",
+			data:     commented,
+			commands: []Command{up, st},
+			want: `# tb config
+commands:
+  # docker stuff
+  - name: up
+    command: docker compose up
+
+  # git stuff
+  - name: st
+    command: git status # short
+# the end
+`,
+		},
+		{
+			name:     "delete only",
+			data:     "commands:\n  # one\n  - name: up\n    command: docker compose up\n",
+			commands: nil,
+			want:     "commands:\n",
+		},
+		{
+			name:     "rename",
+			data:     commented,
+			commands: []Command{up, cmd("status", "git status"), log},
+			want: `# tb config
+commands:
+  # docker stuff
+  - name: up
+    command: docker compose up
+
+  # git stuff
+  - name: status
+    command: git status # short
+
+  - name: log
+    command: git log
+# the end
+`,
+		},
+		{
+			name:     "add",
+			data:     commented,
+			commands: []Command{up, st, log, {Name: "diff", Command: "git diff", Tags: []string{"git"}}},
+			want: `# tb config
+commands:
+  # docker stuff
+  - name: up
+    command: docker compose up
+
+  # git stuff
+  - name: st
+    command: git status # short
+
+  - name: log
+    command: git log
+
+  - name: diff
+    command: git diff
+    tags:
+      - git
+# the end
+`,
+		},
+		{
+			name:     "flow item",
+			data:     "commands:\n  - {name: up, command: docker compose up}\n  - name: st\n    command: git status\n",
+			commands: []Command{cmd("up", "docker compose up -d"), st},
+			want:     "commands:\n  - {name: up, command: docker compose up -d}\n  - name: st\n    command: git status\n",
+		},
+		{
+			name:     "unindented list",
+			data:     "commands:\n- name: up\n  command: docker compose up\n# git\n- name: st\n  command: git status\n",
+			commands: []Command{cmd("st", "git status -s"), cmd("log", "git log")},
+			want:     "commands:\n# git\n- name: st\n  command: git status -s\n- name: log\n  command: git log\n",
+		},
+		{
+			name:     "missing commands",
+			data:     "# nothing yet\n",
+			commands: []Command{up},
+			want:     "# nothing yet\ncommands:\n  - name: up\n    command: docker compose up\n",
+		},
+		{
+			name:     "null commands",
+			data:     "commands:\n# keep\n",
+			commands: []Command{up},
+			want:     "commands:\n  - name: up\n    command: docker compose up\n# keep\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := spliceCommands([]byte(tt.data), tt.commands)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchEntries(t *testing.T) {
+	a, b, c := cmd("a", "1"), cmd("b", "2"), cmd("c", "3")
+	tests := []struct {
+		name       string
+		disk, cmds []Command
+		want       []int
+	}{
+		{"same", []Command{a, b}, []Command{a, b}, []int{0, 1}},
+		{"moved", []Command{a, b}, []Command{b, a}, []int{1, 0}},
+		{"deleted", []Command{a, b, c}, []Command{a, c}, []int{0, 2}},
+		{"renamed", []Command{a, b}, []Command{a, cmd("x", "2")}, []int{0, 1}},
+		{"added", []Command{a}, []Command{a, b}, []int{0, -1}},
+		{"empty file", nil, []Command{a}, []int{-1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchEntries(tt.disk, tt.cmds); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	a, b, c := cmd("a", "1"), cmd("b", "2"), cmd("c", "3")
+	tests := []struct {
+		name                string
+		disk, before, after []Command
+		want                []Command
+		conflicts           []string
+	}{
+		{
+			name:   "added there",
+			disk:   []Command{a, b, c},
+			before: []Command{a, b},
+			after:  []Command{a, cmd("b", "two")},
+			want:   []Command{a, cmd("b", "two"), c},
+		},
+		{
+			name:   "edited there",
+			disk:   []Command{cmd("a", "one"), b},
+			before: []Command{a, b},
+			after:  []Command{a, b, c},
+			want:   []Command{cmd("a", "one"), b, c},
+		},
+		{
+			name:   "deleted there",
+			disk:   []Command{b},
+			before: []Command{a, b},
+			after:  []Command{a, cmd("b", "two")},
+			want:   []Command{cmd("b", "two")},
+		},
+		{
+			name:   "deleted here",
+			disk:   []Command{a, b, c},
+			before: []Command{a, b},
+			after:  []Command{b},
+			want:   []Command{b, c},
+		},
+		{
+			name:   "undo of a delete",
+			disk:   []Command{a, c},
+			before: []Command{a, c},
+			after:  []Command{a, b, c},
+			want:   []Command{a, b, c},
+		},
+		{
+			name:      "edited on both sides",
+			disk:      []Command{cmd("a", "one"), b},
+			before:    []Command{a, b},
+			after:     []Command{cmd("a", "uno"), b},
+			want:      []Command{cmd("a", "uno"), b},
+			conflicts: []string{"a"},
+		},
+		{
+			name:      "edited here, deleted there",
+			disk:      []Command{b},
+			before:    []Command{a, b},
+			after:     []Command{cmd("a", "uno"), b},
+			want:      []Command{b},
+			conflicts: []string{"a"},
+		},
+		{
+			name:      "deleted here, edited there",
+			disk:      []Command{cmd("a", "one"), b},
+			before:    []Command{a, b},
+			after:     []Command{b},
+			want:      []Command{b},
+			conflicts: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := rebase(tt.disk, tt.before, tt.after)
+			if !reflect.DeepEqual(conflicts, tt.conflicts) {
+				t.Errorf("conflicts %v, want %v", conflicts, tt.conflicts)
+			}
+			if !sameCommands(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		cmd  Command
+		want string
+	}{
+		{
+			name: "new",
+			cmd:  Command{Name: "up", Command: "docker compose up", Pinned: true},
+			want: "  - name: up\n    command: docker compose up\n    pinned: true\n",
+		},
+		{
+			name: "keeps key order and unknown keys",
+			old:  "command: git status\nname: st # short\nnote: mine\n",
+			cmd:  Command{Name: "st", Command: "git status -s"},
+			want: "  - command: git status -s\n    name: st # short\n    note: mine\n",
+		},
+		{
+			name: "drops emptied fields",
+			old:  "name: st\ndescription: status\ncommand: git status\ncategory: git\n",
+			cmd:  Command{Name: "st", Command: "git status"},
+			want: "  - name: st\n    command: git status\n",
+		},
+		{
+			name: "keeps inline lists",
+			old:  "name: st\ncommand: git status\ntags: [git]\n",
+			cmd:  Command{Name: "st", Command: "git status", Tags: []string{"git", "vcs"}},
+			want: "  - name: st\n    command: git status\n    tags: [git, vcs]\n",
+		},
+		{
+			name: "keeps literal blocks",
+			old:  "name: deploy\ncommand: |\n  make\n  make push\n",
+			cmd:  Command{Name: "deploy", Command: "make\nmake test\nmake push\n"},
+			want: "  - name: deploy\n    command: |\n      make\n      make test\n      make push\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var old *yaml.Node
+			if tt.old != "" {
+				var doc yaml.Node
+				if err := yaml.Unmarshal([]byte(tt.old), &doc); err != nil {
+					t.Fatal(err)
+				}
+				old = doc.Content[0]
+			}
+			got, err := encodeEntry(old, tt.cmd, "  ", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCommandsConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tb.yaml")
+	disk := "commands:\n  - name: a\n    command: one\n"
+	if err := os.WriteFile(path, []byte(disk), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := writeCommands(path, []Command{cmd("a", "1")}, []Command{cmd("a", "uno")})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got %v, want a *ConflictError", err)
+	}
+	if !reflect.DeepEqual(conflict.Names, []string{"a"}) {
+		t.Errorf("conflicting names %v, want [a]", conflict.Names)
+	}
+	if data, _ := os.ReadFile(path); string(data) != disk {
+		t.Errorf("file changed on conflict:\n%s", data)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !(unix && !aix) && !windows
+
+package config
+
+import "os"
+
+// lockFile is a no-op on platforms without advisory locks.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
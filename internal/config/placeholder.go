@@ -0,0 +1,43 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// placeholderRe matches {{name}} placeholders, allowing spaces inside the braces.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+// Placeholder configures how the value of a {{name}} placeholder is chosen.
+type Placeholder struct {
+	// Source is a shell command whose output lines are offered as choices.
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+	// Default prefills the input.
+	Default string `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// PlaceholderNames returns the unique placeholder names in the command, in order of first appearance.
+func (c Command) PlaceholderNames() []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, m := range placeholderRe.FindAllStringSubmatch(c.Command, -1) {
+		if _, ok := seen[m[1]]; ok {
+			continue
+		}
+		seen[m[1]] = struct{}{}
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// Fill returns the command text with each placeholder replaced by its value.
+// Placeholders without an entry in values are left untouched.
+func (c Command) Fill(values map[string]string) string {
+	return placeholderRe.ReplaceAllStringFunc(c.Command, func(s string) string {
+		name := strings.TrimSpace(s[2 : len(s)-2])
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return s
+	})
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"tb/internal/fsutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTrashDays is how long deleted commands are kept when the config
+// doesn't set trash_days.
+const defaultTrashDays = 30
+
+// TrashEntry is a deleted command kept in the trash file.
+type TrashEntry struct {
+	Command   `yaml:",inline"`
+	File      string    `yaml:"file"` // config file it was deleted from
+	DeletedAt time.Time `yaml:"deleted_at"`
+}
+
+// trashPath returns $XDG_STATE_HOME/tb/trash.yaml (default
+// ~/.local/state/tb/trash.yaml), next to the selection history.
+func trashPath() (string, error) {
+	dir, err := fsutil.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "trash.yaml"), nil
+}
+
+// trashAge is how long entries stay in the trash; zero means forever.
+func (c *Config) trashAge() time.Duration {
+	switch {
+	case c.TrashDays < 0:
+		return 0
+	case c.TrashDays == 0:
+		return defaultTrashDays * 24 * time.Hour
+	}
+	return time.Duration(c.TrashDays) * 24 * time.Hour
+}
+
+// MoveToTrash records deleted commands in the trash. Call it once the
+// deletion is saved.
+func (c *Config) MoveToTrash(deleted ...Command) error {
+	now := time.Now()
+	return c.updateTrash(func(entries []TrashEntry) ([]TrashEntry, error) {
+		for _, cmd := range deleted {
+			file := cmd.Origin
+			if file == "" {
+				file = c.path
+			}
+			cmd.Origin = ""
+			entries = append(entries, TrashEntry{Command: cmd, File: file, DeletedAt: now})
+		}
+		return entries, nil
+	})
+}
+
+// Trash returns the commands in the trash, oldest first.
+func (c *Config) Trash() ([]TrashEntry, error) {
+	var out []TrashEntry
+	err := c.updateTrash(func(entries []TrashEntry) ([]TrashEntry, error) {
+		out = entries
+		return entries, nil
+	})
+	return out, err
+}
+
+// RestoreFromTrash saves the most recently deleted command called name back
+// to the file it was deleted from, or to the global config if that file is
+// no longer loaded, and takes it out of the trash.
+func (c *Config) RestoreFromTrash(name string) (Command, error) {
+	var restored Command
+	err := c.updateTrash(func(entries []TrashEntry) ([]TrashEntry, error) {
+		i := lastTrashed(entries, name)
+		if i < 0 {
+			return nil, fmt.Errorf("no command named %q in the trash", name)
+		}
+		restored = entries[i].Command
+		restored.Origin = c.path
+		if slices.Contains(c.files, entries[i].File) {
+			restored.Origin = entries[i].File
+		}
+		if err := Validate(c.Commands, restored, -1); err != nil {
+			return nil, err
+		}
+		if err := c.Save(append(slices.Clone(c.Commands), restored)); err != nil {
+			return nil, err
+		}
+		return slices.Delete(entries, i, i+1), nil
+	})
+	return restored, err
+}
+
+// Untrash takes the most recent entry for each name out of the trash, for
+// deletions that were undone. Names that aren't in the trash are skipped.
+func (c *Config) Untrash(names ...string) error {
+	return c.updateTrash(func(entries []TrashEntry) ([]TrashEntry, error) {
+		for _, name := range names {
+			if i := lastTrashed(entries, name); i >= 0 {
+				entries = slices.Delete(entries, i, i+1)
+			}
+		}
+		return entries, nil
+	})
+}
+
+// lastTrashed returns the index of the newest entry called name, or -1.
+func lastTrashed(entries []TrashEntry, name string) int {
+	i := len(entries) - 1
+	for i >= 0 && entries[i].Name != name {
+		i--
+	}
+	return i
+}
+
+// updateTrash rewrites the trash file with fn's result, under a lock, after
+// dropping entries older than the configured age. If fn fails, the file is
+// left as it was.
+func (c *Config) updateTrash(fn func([]TrashEntry) ([]TrashEntry, error)) error {
+	path, err := trashPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return withLock(path, func() error {
+		var entries []TrashEntry
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if age := c.trashAge(); age > 0 {
+			cutoff := time.Now().Add(-age)
+			entries = slices.DeleteFunc(entries, func(e TrashEntry) bool { return e.DeletedAt.Before(cutoff) })
+		}
+
+		entries, err = fn(entries)
+		if err != nil {
+			return err
+		}
+		updated, err := yaml.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		if string(updated) == string(data) {
+			return nil
+		}
+		return fsutil.WriteFileAtomic(path, updated, 0644)
+	})
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"time"
+)
+
+// fileStamp identifies a version of a file on disk. The zero value means
+// the file doesn't exist.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// Changed reports whether any loaded file was modified, created or removed
+// by something other than this Config since it was loaded, or a new file
+// turned up in commands.d, an include pattern or the project directories.
+func (c *Config) Changed() bool {
+	for _, path := range c.watched() {
+		if s := stampOf(path); !s.modTime.Equal(c.stamps[path].modTime) || s.size != c.stamps[path].size {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload re-reads the config files in place. If they no longer load, the
+// config keeps its current commands and the error is returned; the change
+// is still recorded, so Changed doesn't keep reporting it.
+func (c *Config) Reload() error {
+	fresh, err := load(c.explicitPath, false)
+	if err != nil {
+		for _, path := range c.watched() {
+			c.stamps[path] = stampOf(path)
+		}
+		return err
+	}
+	*c = *fresh
+	return nil
+}
+
+// watched returns the loaded files followed by the files a reload would
+// read now, which differ when one was added since the config was loaded.
+func (c *Config) watched() []string {
+	probe := &Config{path: c.path, Include: c.Include}
+	extra, err := probe.extraFiles()
+	if err != nil {
+		return c.files
+	}
+	return append(slices.Clone(c.files), extra...)
+}
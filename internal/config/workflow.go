@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// IsWorkflow reports whether cmd runs other commands rather than its own.
+func (c Command) IsWorkflow() bool {
+	return len(c.Steps) > 0
+}
+
+// Expand returns the commands cmd runs, in order, with nested workflows
+// expanded in place. A plain command expands to itself.
+func Expand(commands []Command, cmd Command) ([]Command, error) {
+	return expand(commands, cmd, nil)
+}
+
+// expand is Expand, with via holding the workflows already being expanded.
+func expand(commands []Command, cmd Command, via []string) ([]Command, error) {
+	if !cmd.IsWorkflow() {
+		return []Command{cmd}, nil
+	}
+	if i := slices.Index(via, cmd.Name); i >= 0 {
+		loop := slices.Concat(via[i:], []string{cmd.Name})
+		return nil, fmt.Errorf("workflow %q runs itself (%s)", cmd.Name, strings.Join(loop, " → "))
+	}
+	via = append(via, cmd.Name)
+	var out []Command
+	for _, name := range cmd.Steps {
+		i := Find(commands, name)
+		if i < 0 {
+			return nil, fmt.Errorf("workflow %q: step %q is not a command", cmd.Name, name)
+		}
+		steps, err := expand(commands, commands[i], via)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, steps...)
+	}
+	return out, nil
+}
+
+// BrokenWorkflows returns the workflows in commands whose steps name a
+// missing command or lead back to a workflow, each with its problem.
+func BrokenWorkflows(commands []Command) map[string]error {
+	broken := make(map[string]error)
+	for _, cmd := range commands {
+		if _, err := Expand(commands, cmd); err != nil {
+			broken[cmd.Name] = err
+		}
+	}
+	return broken
+}
+
+// CheckSteps returns an error for the first workflow in after that is
+// broken but wasn't in before, so a change can't break a workflow but
+// doesn't have to fix one that already was.
+func CheckSteps(before, after []Command) error {
+	was := BrokenWorkflows(before)
+	for _, cmd := range after {
+		if _, err := Expand(after, cmd); err != nil && was[cmd.Name] == nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithSteps returns targets plus the commands their workflows run, directly
+// or through nested workflows, in the order they appear in commands, so the
+// workflows keep working wherever the result is loaded. Missing steps are
+// skipped.
+func WithSteps(commands, targets []Command) []Command {
+	need := make(map[string]bool)
+	var add func(cmd Command)
+	add = func(cmd Command) {
+		if need[cmd.Name] {
+			return
+		}
+		need[cmd.Name] = true
+		for _, name := range cmd.Steps {
+			if i := Find(commands, name); i >= 0 {
+				add(commands[i])
+			}
+		}
+	}
+	for _, cmd := range targets {
+		add(cmd)
+	}
+	var out []Command
+	for _, cmd := range commands {
+		if need[cmd.Name] {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+// Resolve returns cmd ready to run. A workflow becomes one command joining
+// its steps with &&, carrying their placeholders, so a placeholder used by
+// several steps is filled in once. The workflow's own placeholders entries
+// take precedence over those of its steps.
+func Resolve(commands []Command, cmd Command) (Command, error) {
+	if !cmd.IsWorkflow() {
+		return cmd, nil
+	}
+	steps, err := Expand(commands, cmd)
+	if err != nil {
+		return cmd, err
+	}
+	lines := make([]string, len(steps))
+	placeholders := maps.Clone(cmd.Placeholders)
+	for i, step := range steps {
+		lines[i] = step.Command
+		for name, p := range step.Placeholders {
+			if _, ok := placeholders[name]; !ok {
+				if placeholders == nil {
+					placeholders = make(map[string]Placeholder)
+				}
+				placeholders[name] = p
+			}
+		}
+	}
+	cmd.Command = strings.Join(lines, " && ")
+	cmd.Placeholders = placeholders
+	return cmd, nil
+}
+
+// RenameStep points the steps that run from at to instead, so renaming a
+// command doesn't break the workflows using it. It returns the indexes of
+// the workflows it changed; their step lists are copied before the change.
+func RenameStep(commands []Command, from, to string) []int {
+	if from == to {
+		return nil
+	}
+	var changed []int
+	for i, cmd := range commands {
+		if !slices.Contains(cmd.Steps, from) {
+			continue
+		}
+		steps := slices.Clone(cmd.Steps)
+		for j, name := range steps {
+			if name == from {
+				steps[j] = to
+			}
+		}
+		commands[i].Steps = steps
+		changed = append(changed, i)
+	}
+	return changed
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tb/internal/fsutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sidecarRe matches the files tb writes next to a config file: backups,
+// the lock file and temp files not yet renamed into place.
+var sidecarRe = regexp.MustCompile(`\.(bak\.[0-9]+|lock|tmp-[0-9]+)$`)
+
+// isSidecar reports whether path is one of tb's own files kept next to a
+// config file, rather than a config file.
+func isSidecar(path string) bool {
+	return sidecarRe.MatchString(path)
+}
+
+// maxBackups is how many previous versions of each file are kept, as
+// <file>.bak.1 (newest) to <file>.bak.N.
+const maxBackups = 5
+
+// Backup is a saved previous version of a config file.
+type Backup struct {
+	N       int
+	Path    string
+	ModTime time.Time
+}
+
+// ConflictError reports commands that a save would change but that were
+// also changed in the file since they were loaded.
+type ConflictError struct {
+	Path  string
+	Names []string
+}
+
+func (e *ConflictError) Error() string {
+	quoted := make([]string, len(e.Names))
+	for i, name := range e.Names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return fmt.Sprintf("%s changed in %s since tb loaded it", strings.Join(quoted, ", "), e.Path)
+}
+
+// writeCommands saves the edits that turned before into after to the file at
+// path. The file is re-read under a lock, so commands another tb session or
+// an editor saved in the meantime are kept, and the version it replaces is
+// backed up. merged reports whether such outside changes were merged in.
+func writeCommands(path string, before, after []Command) (merged bool, err error) {
+	err = withLock(path, func() error {
+		target := realPath(path)
+		data, err := os.ReadFile(target)
+		var out []byte
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			data = nil
+			out, err = yaml.Marshal(&Config{Commands: after})
+		case err == nil:
+			var disk Config
+			if err = yaml.Unmarshal(data, &disk); err != nil {
+				break
+			}
+			commands, conflicts := rebase(disk.Commands, before, after)
+			if len(conflicts) > 0 {
+				return &ConflictError{Path: path, Names: conflicts}
+			}
+			merged = !sameCommands(commands, after)
+			out, err = spliceCommands(data, commands)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if data != nil {
+			if bytes.Equal(data, out) {
+				return nil
+			}
+			if err := backup(path, data, fileMode(target)); err != nil {
+				return fmt.Errorf("backup %s: %w", path, err)
+			}
+		}
+		return fsutil.WriteFileAtomic(target, out, fileMode(target))
+	})
+	return merged, err
+}
+
+// Backups lists the backups of the config file at path, newest first.
+func Backups(path string) ([]Backup, error) {
+	matches, err := filepath.Glob(path + ".bak.*")
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	for _, m := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(m, path+".bak."))
+		if err != nil || n < 1 {
+			continue
+		}
+		info, err := os.Stat(m)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{N: n, Path: m, ModTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].N < backups[j].N })
+	return backups, nil
+}
+
+// Restore replaces the config file at path with its backup n. The current
+// version becomes backup 1, so a restore can itself be rolled back.
+func Restore(path string, n int) error {
+	return withLock(path, func() error {
+		data, err := os.ReadFile(backupPath(path, n))
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no backup %d of %s", n, path)
+		} else if err != nil {
+			return err
+		}
+		target := realPath(path)
+		perm := fileMode(target)
+		if current, err := os.ReadFile(target); err == nil {
+			if err := backup(path, current, perm); err != nil {
+				return fmt.Errorf("backup %s: %w", path, err)
+			}
+		}
+		return fsutil.WriteFileAtomic(target, data, perm)
+	})
+}
+
+func backupPath(path string, n int) string {
+	return path + ".bak." + strconv.Itoa(n)
+}
+
+// backup shifts the existing backups of path up by one, dropping the oldest,
+// and saves data as backup 1 with the file's permissions.
+func backup(path string, data []byte, perm os.FileMode) error {
+	for n := maxBackups - 1; n >= 1; n-- {
+		err := os.Rename(backupPath(path, n), backupPath(path, n+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return fsutil.WriteFileAtomic(backupPath(path, 1), data, perm)
+}
+
+// withLock runs fn while holding an exclusive lock on path. The lock is
+// taken on a separate <file>.lock, since the file itself is replaced by
+// every write.
+func withLock(path string, fn func() error) error {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("lock %s: %w", path, err)
+	}
+	defer unlockFile(f)
+	return fn()
+}
+
+// realPath follows symlinks, so a config linked from a dotfiles repo is
+// updated there rather than replaced by a regular file.
+func realPath(path string) string {
+	if p, err := filepath.EvalSymlinks(path); err == nil {
+		return p
+	}
+	return path
+}
+
+// fileMode returns the permissions of the file at path, or the default for
+// a new config file.
+func fileMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
+}
@@ -0,0 +1,148 @@
+// Package history records command selections and ranks commands by frecency.
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tb/internal/fsutil"
+)
+
+// maxEntries is how many selections are kept; older ones are dropped on compaction.
+const maxEntries = 1000
+
+type entry struct {
+	name string
+	at   time.Time
+}
+
+// Store is an append-only log of selections, one "unix-time<TAB>name" line each.
+type Store struct {
+	path    string
+	entries []entry
+	lines   int // lines in the file, which keeps growing until compacted
+}
+
+// Load reads the history file from $XDG_STATE_HOME/tb/history
+// (default ~/.local/state/tb/history). It always returns a usable store;
+// the error reports a history file that exists but could not be read.
+func Load() (*Store, error) {
+	s := &Store{}
+	dir, err := fsutil.StateDir()
+	if err != nil {
+		return s, err
+	}
+	s.path = filepath.Join(dir, "history")
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s.lines++
+		ts, name, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			continue
+		}
+		s.entries = append(s.entries, entry{name: name, at: time.Unix(sec, 0)})
+	}
+	if len(s.entries) > maxEntries {
+		s.entries = s.entries[len(s.entries)-maxEntries:]
+	}
+	return s, scanner.Err()
+}
+
+// Record appends a selection of the named command to the history.
+func (s *Store) Record(name string) error {
+	if s.path == "" {
+		return nil
+	}
+	now := time.Now()
+	s.entries = append(s.entries, entry{name: name, at: now})
+	if len(s.entries) > maxEntries {
+		s.entries = s.entries[len(s.entries)-maxEntries:]
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%d\t%s\n", now.Unix(), name)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	s.lines++
+
+	// Rewrite the file once it has grown well past the cap.
+	if s.lines > 2*maxEntries {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file with only its last maxEntries lines. It reads
+// the file again rather than writing s.entries, so selections appended by
+// other sessions since Load are kept.
+func (s *Store) compact() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) > maxEntries {
+		lines = lines[len(lines)-maxEntries:]
+	}
+	if err := fsutil.WriteFileAtomic(s.path, []byte(strings.Join(lines, "")+"\n"), 0644); err != nil {
+		return err
+	}
+	s.lines = len(lines)
+	return nil
+}
+
+// Scores returns a frecency score per command name: every selection counts,
+// weighted by how recent it is.
+func (s *Store) Scores(now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, e := range s.entries {
+		scores[e.name] += weight(now.Sub(e.at))
+	}
+	return scores
+}
+
+// weight buckets a selection's age, favouring the last few days.
+func weight(age time.Duration) float64 {
+	const day = 24 * time.Hour
+	switch {
+	case age < 4*day:
+		return 100
+	case age < 14*day:
+		return 70
+	case age < 31*day:
+		return 50
+	case age < 90*day:
+		return 30
+	default:
+		return 10
+	}
+}
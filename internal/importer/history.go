@@ -0,0 +1,191 @@
+// Package importer converts between tb commands and other sources: shell
+// history, navi cheatsheets and pet snippet files.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HistoryEntry is a distinct command line from shell history.
+type HistoryEntry struct {
+	Command string
+	Count   int // how many times it appears across all history files
+}
+
+// ShellHistory reads the bash, zsh and fish history files under home and
+// returns their distinct multi-word commands, most frequent first.
+func ShellHistory(home string) ([]HistoryEntry, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	sources := []struct {
+		path  string
+		parse func(io.Reader) ([]string, error)
+	}{
+		{filepath.Join(home, ".bash_history"), ParseBash},
+		{filepath.Join(home, ".zsh_history"), ParseZsh},
+		{filepath.Join(home, ".zhistory"), ParseZsh},
+		{filepath.Join(dataHome, "fish", "fish_history"), ParseFish},
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, src := range sources {
+		f, err := os.Open(src.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		lines, err := src.parse(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			// Single words (ls, htop, ...) are quicker to type than to look up.
+			if !strings.ContainsAny(line, " \t\n") {
+				continue
+			}
+			if counts[line] == 0 {
+				order = append(order, line)
+			}
+			counts[line]++
+		}
+	}
+
+	entries := make([]HistoryEntry, len(order))
+	for i, cmd := range order {
+		entries[i] = HistoryEntry{Command: cmd, Count: counts[cmd]}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Count > entries[j].Count
+	})
+	return entries, nil
+}
+
+// ParseBash reads ~/.bash_history, skipping the "#<unix time>" lines bash
+// writes when HISTTIMEFORMAT is set.
+func ParseBash(r io.Reader) ([]string, error) {
+	var cmds []string
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if isBashTimestamp(line) || strings.TrimSpace(line) == "" {
+			continue
+		}
+		cmds = append(cmds, line)
+	}
+	return cmds, scanner.Err()
+}
+
+func isBashTimestamp(line string) bool {
+	if len(line) < 2 || line[0] != '#' {
+		return false
+	}
+	for _, r := range line[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseZsh reads a zsh history file in plain or extended (": <ts>:<dur>;cmd")
+// format. Lines ending in a backslash continue the command on the next line.
+func ParseZsh(r io.Reader) ([]string, error) {
+	var cmds []string
+	var cur []string
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		line := unmetafy(scanner.Bytes())
+		if len(cur) == 0 && strings.HasPrefix(line, ": ") {
+			if _, cmd, ok := strings.Cut(line, ";"); ok {
+				line = cmd
+			}
+		}
+		if strings.HasSuffix(line, `\`) {
+			cur = append(cur, strings.TrimSuffix(line, `\`))
+			continue
+		}
+		cur = append(cur, line)
+		if cmd := strings.Join(cur, "\n"); strings.TrimSpace(cmd) != "" {
+			cmds = append(cmds, cmd)
+		}
+		cur = cur[:0]
+	}
+	return cmds, scanner.Err()
+}
+
+// unmetafy undoes zsh's history encoding, which stores bytes 0x83-0xa2 as
+// 0x83 followed by the byte XOR 32.
+func unmetafy(b []byte) string {
+	if bytes.IndexByte(b, 0x83) < 0 {
+		return string(b)
+	}
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == 0x83 && i+1 < len(b) {
+			i++
+			out = append(out, b[i]^32)
+			continue
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
+
+// ParseFish reads fish_history, a YAML-like file of "- cmd: ..." entries.
+// It is not strict YAML, so only the cmd lines are picked out.
+func ParseFish(r io.Reader) ([]string, error) {
+	var cmds []string
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		cmd, ok := strings.CutPrefix(scanner.Text(), "- cmd: ")
+		if !ok {
+			continue
+		}
+		cmds = append(cmds, unescapeFish(cmd))
+	}
+	return cmds, scanner.Err()
+}
+
+// unescapeFish reverses the \n and \\ escapes fish applies to history entries.
+func unescapeFish(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// newLineScanner returns a line scanner that tolerates very long history lines.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return scanner
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxNameWords caps how many words of a command go into a suggested name.
+const maxNameWords = 3
+
+// SuggestName derives a short kebab-case name from a command line, e.g.
+// "sudo kubectl get pods -n prod" becomes "kubectl-get-pods". taken holds
+// names already in use; a numeric suffix keeps the result unique, and the
+// chosen name is added to taken.
+func SuggestName(command string, taken map[string]bool) string {
+	var words []string
+	for _, field := range strings.Fields(command) {
+		// Stop at the first pipe, separator or redirection.
+		if len(words) == maxNameWords || strings.ContainsAny(field, "|;&<>") {
+			break
+		}
+		if len(words) == 0 && (field == "sudo" || strings.Contains(field, "=")) {
+			continue // skip sudo and leading VAR=value assignments
+		}
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		if w := slug(field); w != "" {
+			words = append(words, w)
+		}
+	}
+
+	base := strings.Join(words, "-")
+	if base == "" {
+		base = "command"
+	}
+
+	name := base
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s-%d", base, n)
+	}
+	taken[name] = true
+	return name
+}
+
+// slug keeps the lowercase letters, digits and dashes of a word.
+func slug(word string) string {
+	// Use only the last path element, so /usr/bin/git becomes git.
+	if i := strings.LastIndex(word, "/"); i >= 0 && i < len(word)-1 {
+		word = word[i+1:]
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			b.WriteRune(r)
+		case r == '_' || r == '.':
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"tb/internal/config"
+)
+
+// naviVarRe matches navi's <var> placeholders.
+var naviVarRe = regexp.MustCompile(`<([A-Za-z_][A-Za-z0-9_-]*)>`)
+
+// ParseNavi reads a navi .cheat file. "% a, b" lines set the tags of the
+// snippets that follow (the first becomes the category), "# text" starts a
+// snippet with a description, and "$ var: command" lines become placeholder
+// sources for every snippet in the file that uses <var>.
+func ParseNavi(r io.Reader) ([]config.Command, error) {
+	var (
+		cmds    []config.Command
+		tags    []string
+		cur     *config.Command
+		lines   []string
+		sources = make(map[string]string)
+	)
+	flush := func() {
+		if cur != nil && len(lines) > 0 {
+			cur.Command = naviVarRe.ReplaceAllString(strings.Join(lines, "\n"), "{{$1}}")
+			cmds = append(cmds, *cur)
+		}
+		cur, lines = nil, nil
+	}
+
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		switch {
+		case strings.HasPrefix(line, "%"):
+			flush()
+			tags = config.ParseTags(strings.TrimSpace(line[1:]))
+		case strings.HasPrefix(line, "#"):
+			flush()
+			cur = &config.Command{Description: strings.TrimSpace(line[1:]), Tags: tags}
+			if len(tags) > 0 {
+				cur.Category = tags[0]
+			}
+		case strings.HasPrefix(line, "$"):
+			flush()
+			name, src, ok := strings.Cut(strings.TrimSpace(line[1:]), ":")
+			if !ok {
+				continue
+			}
+			// Drop navi's "--- --column 2" style selector options.
+			src, _, _ = strings.Cut(src, " --- ")
+			sources[strings.TrimSpace(name)] = strings.TrimSpace(src)
+		case strings.HasPrefix(line, ";"), strings.HasPrefix(line, "@"):
+			// Comments and cheat extensions have no tb equivalent.
+		case line == "":
+			flush()
+		default:
+			if cur == nil {
+				cur = &config.Command{Tags: tags}
+				if len(tags) > 0 {
+					cur.Category = tags[0]
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range cmds {
+		for _, name := range cmds[i].PlaceholderNames() {
+			if src, ok := sources[name]; ok {
+				if cmds[i].Placeholders == nil {
+					cmds[i].Placeholders = make(map[string]config.Placeholder)
+				}
+				cmds[i].Placeholders[name] = config.Placeholder{Source: src}
+			}
+		}
+	}
+	return cmds, nil
+}
+
+// WriteNavi writes commands as a navi cheatsheet. Each command gets its own
+// "%" header so its category and tags survive, and placeholder sources are
+// written as "$ var: command" lines after it.
+func WriteNavi(w io.Writer, commands []config.Command) error {
+	for i, cmd := range commands {
+		tags := cmd.Tags
+		if cmd.Category != "" && !slices.Contains(tags, cmd.Category) {
+			tags = append([]string{cmd.Category}, tags...)
+		}
+		if len(tags) == 0 {
+			tags = []string{"tb"}
+		}
+		desc := cmd.Description
+		if desc == "" {
+			desc = cmd.Name
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%% %s\n\n", strings.Join(tags, ", "))
+		fmt.Fprintf(w, "# %s\n", strings.ReplaceAll(desc, "\n", " "))
+		fmt.Fprintln(w, toAngleVars(cmd, false))
+		for _, name := range cmd.PlaceholderNames() {
+			if src := cmd.Placeholders[name].Source; src != "" {
+				fmt.Fprintf(w, "\n$ %s: %s\n", name, src)
+			}
+		}
+	}
+	return nil
+}
+
+// toAngleVars rewrites {{var}} placeholders as <var>, the syntax navi and pet
+// share. With defaults set, placeholders that have one become <var=default>.
+func toAngleVars(cmd config.Command, defaults bool) string {
+	values := make(map[string]string)
+	for _, name := range cmd.PlaceholderNames() {
+		values[name] = "<" + name + ">"
+		if def := cmd.Placeholders[name].Default; defaults && def != "" {
+			values[name] = "<" + name + "=" + def + ">"
+		}
+	}
+	return cmd.Fill(values)
+}
+
+// Named gives every unnamed command a unique name derived from its
+// description (or command line), avoiding the names in taken.
+func Named(commands []config.Command, taken map[string]bool) []config.Command {
+	for i, cmd := range commands {
+		switch {
+		case cmd.Name != "" && !taken[cmd.Name]:
+			taken[cmd.Name] = true
+		case cmd.Description != "":
+			commands[i].Name = SuggestName(cmd.Description, taken)
+		default:
+			commands[i].Name = SuggestName(cmd.Command, taken)
+		}
+	}
+	return commands
+}
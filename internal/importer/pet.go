@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"tb/internal/config"
+
+	"github.com/BurntSushi/toml"
+)
+
+// petVarRe matches pet's <param> and <param=default> placeholders.
+var petVarRe = regexp.MustCompile(`<([A-Za-z_][A-Za-z0-9_-]*)(?:=([^<>]*))?>`)
+
+// petFile is the layout of a pet snippet.toml. Keys tb has no use for,
+// such as output, are ignored.
+type petFile struct {
+	Snippets []struct {
+		Description string   `toml:"description"`
+		Command     string   `toml:"command"`
+		Tag         []string `toml:"tag"`
+	} `toml:"snippets"`
+}
+
+// ParsePet reads a pet snippet.toml. The first tag becomes the category.
+func ParsePet(r io.Reader) ([]config.Command, error) {
+	var file petFile
+	if _, err := toml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	var cmds []config.Command
+	for _, snippet := range file.Snippets {
+		cmd := config.Command{
+			Description: snippet.Description,
+			Tags:        snippet.Tag,
+		}
+		if len(cmd.Tags) > 0 {
+			cmd.Category = cmd.Tags[0]
+		}
+		cmd.Command = petVarRe.ReplaceAllStringFunc(snippet.Command, func(s string) string {
+			m := petVarRe.FindStringSubmatch(s)
+			if m[2] != "" {
+				if cmd.Placeholders == nil {
+					cmd.Placeholders = make(map[string]config.Placeholder)
+				}
+				cmd.Placeholders[m[1]] = config.Placeholder{Default: m[2]}
+			}
+			return "{{" + m[1] + "}}"
+		})
+		if strings.TrimSpace(cmd.Command) != "" {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds, nil
+}
+
+// WritePet writes commands as a pet snippet.toml. The category is written as
+// the first tag, and placeholder defaults as <param=default>.
+func WritePet(w io.Writer, commands []config.Command) error {
+	for i, cmd := range commands {
+		tags := cmd.Tags
+		if cmd.Category != "" && !slices.Contains(tags, cmd.Category) {
+			tags = append([]string{cmd.Category}, tags...)
+		}
+		quoted := make([]string, len(tags))
+		for j, tag := range tags {
+			quoted[j] = tomlQuote(tag)
+		}
+		desc := cmd.Description
+		if desc == "" {
+			desc = cmd.Name
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "[[snippets]]")
+		fmt.Fprintf(w, "  description = %s\n", tomlQuote(desc))
+		fmt.Fprintf(w, "  command = %s\n", tomlQuote(toAngleVars(cmd, true)))
+		fmt.Fprintf(w, "  tag = [%s]\n", strings.Join(quoted, ", "))
+		fmt.Fprintln(w, `  output = ""`)
+	}
+	return nil
+}
+
+// tomlQuote renders s as a TOML basic string.
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package lint
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyNames holds every name tea.KeyMsg.String() can produce for a special key.
+var keyNames = func() map[string]bool {
+	names := make(map[string]bool)
+	// Special keys are negative, control keys span the ASCII control range.
+	for k := tea.KeyType(-128); k <= 127; k++ {
+		if name := k.String(); name != "" && k != tea.KeyRunes {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// ValidKey reports whether a keybinding string can ever equal the String()
+// of a tea.KeyMsg: a special key name such as "enter" or "ctrl+k", or a
+// single character, optionally prefixed with "alt+".
+func ValidKey(s string) bool {
+	s = strings.TrimPrefix(s, "alt+")
+	return keyNames[s] || utf8.RuneCountInString(s) == 1
+}
+
+// suggestKey guesses the intended spelling of an invalid key, or returns "".
+func suggestKey(s string) string {
+	if s == "space" {
+		return " " // tea reports the space bar as a literal space
+	}
+	if lower := strings.ToLower(s); ValidKey(lower) {
+		return lower
+	}
+	return ""
+}
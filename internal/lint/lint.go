@@ -0,0 +1,421 @@
+// Package lint checks tb config files and reports problems with their
+// file:line:column positions.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tb/internal/config"
+	"tb/internal/strdist"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity tells whether a diagnostic should fail the lint run.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is one problem found in a config file.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int // 0 if unknown
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	pos := fmt.Sprintf("%s:%d", d.File, d.Line)
+	if d.Column > 0 {
+		pos += ":" + strconv.Itoa(d.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+}
+
+// HasErrors reports whether any diagnostic is an error rather than a warning.
+func HasErrors(diags []Diagnostic) bool {
+	return slices.ContainsFunc(diags, func(d Diagnostic) bool { return d.Severity == Error })
+}
+
+// Known keys, derived from the yaml tags so they can't drift from the config types.
+var (
+	configKeys      = yamlKeys(reflect.TypeFor[config.Config]())
+	commandKeys     = yamlKeys(reflect.TypeFor[config.Command]())
+	placeholderKeys = yamlKeys(reflect.TypeFor[config.Placeholder]())
+	keybindingKeys  = yamlKeys(reflect.TypeFor[config.Keybindings]())
+	categoryKeys    = yamlKeys(reflect.TypeFor[config.CategoryInfo]())
+)
+
+func yamlKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if f.IsExported() && name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// linter accumulates diagnostics across files, remembering command names so
+// duplicates between a config and its packs are caught too.
+type linter struct {
+	file      string
+	names     map[string]string // command name -> "file:line" of its first definition
+	workflows []workflow        // checked once every file is read, as steps may name commands in any file
+	diags     []Diagnostic
+}
+
+// workflow is a command with steps, as found in one file.
+type workflow struct {
+	file  string
+	name  string
+	key   *yaml.Node   // the steps key
+	steps []*yaml.Node // the step names
+}
+
+// Files lints each config file in order. Missing files are skipped; other
+// read failures are returned as an error.
+func Files(paths []string) ([]Diagnostic, error) {
+	l := &linter{names: make(map[string]string)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		l.lintFile(path, data)
+	}
+	l.lintWorkflows()
+	return l.diags, nil
+}
+
+func (l *linter) report(n *yaml.Node, sev Severity, format string, args ...any) {
+	l.diags = append(l.diags, Diagnostic{
+		File:     l.file,
+		Line:     n.Line,
+		Column:   n.Column,
+		Severity: sev,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// yamlLineRe extracts the line number yaml.v3 puts in its error messages.
+var yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func (l *linter) lintFile(path string, data []byte) {
+	l.file = path
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		l.yamlError(err)
+		return
+	}
+	if len(doc.Content) == 0 {
+		return // empty file
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		l.report(root, Error, "config must be a mapping with a commands list")
+		return
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		switch k.Value {
+		case "commands":
+			l.lintCommands(v)
+		case "keybindings":
+			l.lintKeybindings(v)
+		case "include":
+			l.lintInclude(v)
+		case "categories":
+			l.lintCategories(v)
+		default:
+			if !slices.Contains(configKeys, k.Value) {
+				l.unknownKey(k, configKeys, "")
+			}
+		}
+	}
+}
+
+// yamlError turns a yaml.v3 error into diagnostics, one per reported line.
+func (l *linter) yamlError(err error) {
+	msgs := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+	for _, msg := range msgs {
+		d := Diagnostic{File: l.file, Line: 1, Severity: Error, Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Message = m[2]
+		}
+		l.diags = append(l.diags, d)
+	}
+}
+
+func (l *linter) unknownKey(k *yaml.Node, known []string, context string) {
+	msg := fmt.Sprintf("unknown key %q", k.Value)
+	if context != "" {
+		msg += " in " + context
+	}
+	if guess := closest(k.Value, known); guess != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", guess)
+	}
+	l.report(k, Error, "%s", msg)
+}
+
+func (l *linter) lintCommands(seq *yaml.Node) {
+	if seq.Kind == yaml.ScalarNode && seq.Tag == "!!null" {
+		return
+	}
+	if seq.Kind != yaml.SequenceNode {
+		l.report(seq, Error, "commands must be a list")
+		return
+	}
+	for _, item := range seq.Content {
+		l.lintCommand(item)
+	}
+}
+
+func (l *linter) lintCommand(item *yaml.Node) {
+	if item.Kind != yaml.MappingNode {
+		l.report(item, Error, "command entry must be a mapping with name and command")
+		return
+	}
+
+	var cmd config.Command
+	if err := item.Decode(&cmd); err != nil {
+		l.yamlError(err)
+		return
+	}
+
+	fields := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(item.Content); i += 2 {
+		k, v := item.Content[i], item.Content[i+1]
+		fields[k.Value] = k
+		if !slices.Contains(commandKeys, k.Value) {
+			l.unknownKey(k, commandKeys, "command")
+		}
+		switch k.Value {
+		case "placeholders":
+			l.lintPlaceholders(cmd, v)
+		case "steps":
+			l.workflows = append(l.workflows, workflow{file: l.file, name: cmd.Name, key: k, steps: v.Content})
+		}
+	}
+
+	switch name := strings.TrimSpace(cmd.Name); {
+	case name == "":
+		l.report(item, Error, "command is missing a name")
+	case l.names[name] != "":
+		l.report(fields["name"], Error, "duplicate name %q (first defined at %s)", name, l.names[name])
+	default:
+		l.names[name] = fmt.Sprintf("%s:%d", l.file, fields["name"].Line)
+	}
+
+	switch hasCommand := strings.TrimSpace(cmd.Command) != ""; {
+	case !hasCommand && !cmd.IsWorkflow():
+		at := item
+		if k := fields["command"]; k != nil {
+			at = k
+		}
+		l.report(at, Error, "command %q has an empty command", cmd.Name)
+	case hasCommand && cmd.IsWorkflow():
+		l.report(fields["command"], Error, "workflow %q runs its steps; leave command empty", cmd.Name)
+	}
+}
+
+func (l *linter) lintPlaceholders(cmd config.Command, m *yaml.Node) {
+	if m.Kind != yaml.MappingNode {
+		return // Decode already reported the type error
+	}
+	used := cmd.PlaceholderNames()
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		// A workflow's placeholders are used by its steps.
+		if !cmd.IsWorkflow() && !slices.Contains(used, k.Value) {
+			l.report(k, Warning, "placeholder %q is not used in the command", k.Value)
+		}
+		for j := 0; v.Kind == yaml.MappingNode && j+1 < len(v.Content); j += 2 {
+			if pk := v.Content[j]; !slices.Contains(placeholderKeys, pk.Value) {
+				l.unknownKey(pk, placeholderKeys, "placeholder")
+			}
+		}
+	}
+}
+
+func (l *linter) lintKeybindings(m *yaml.Node) {
+	if m.Kind != yaml.MappingNode {
+		l.report(m, Error, "keybindings must be a mapping of action to key list")
+		return
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		if !slices.Contains(keybindingKeys, k.Value) {
+			l.unknownKey(k, keybindingKeys, "keybindings")
+			continue
+		}
+		if v.Kind != yaml.SequenceNode {
+			l.report(v, Error, "keybinding %q must be a list of keys, e.g. [\"ctrl+k\"]", k.Value)
+			continue
+		}
+		for _, keyNode := range v.Content {
+			if !ValidKey(keyNode.Value) {
+				msg := fmt.Sprintf("key %q can never match a key press", keyNode.Value)
+				if guess := suggestKey(keyNode.Value); guess != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", guess)
+				}
+				l.report(keyNode, Error, "%s", msg)
+			}
+		}
+	}
+}
+
+func (l *linter) lintInclude(seq *yaml.Node) {
+	if seq.Kind != yaml.SequenceNode {
+		l.report(seq, Error, "include must be a list of glob patterns")
+		return
+	}
+	for _, n := range seq.Content {
+		if _, err := filepath.Match(n.Value, ""); err != nil {
+			l.report(n, Error, "invalid include pattern %q: %v", n.Value, err)
+		}
+	}
+}
+
+func (l *linter) lintCategories(seq *yaml.Node) {
+	if seq.Kind != yaml.SequenceNode {
+		l.report(seq, Error, "categories must be a list of category settings")
+		return
+	}
+	seen := make(map[string]int) // category name -> line of its first entry
+	for _, item := range seq.Content {
+		if item.Kind != yaml.MappingNode {
+			l.report(item, Error, "category entry must be a mapping with a name")
+			continue
+		}
+		var info config.CategoryInfo
+		if err := item.Decode(&info); err != nil {
+			l.yamlError(err)
+			continue
+		}
+		var nameKey *yaml.Node
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			k := item.Content[i]
+			if k.Value == "name" {
+				nameKey = k
+			}
+			if !slices.Contains(categoryKeys, k.Value) {
+				l.unknownKey(k, categoryKeys, "category")
+			}
+		}
+		switch {
+		case info.Name == "":
+			l.report(item, Error, "category entry is missing a name")
+		case seen[info.Name] != 0:
+			l.report(nameKey, Warning, "category %q is already listed at line %d; this entry is ignored", info.Name, seen[info.Name])
+		default:
+			seen[info.Name] = nameKey.Line
+		}
+	}
+}
+
+// lintWorkflows reports steps naming a command no file defines, and
+// workflows whose steps lead back to themselves.
+func (l *linter) lintWorkflows() {
+	steps := make(map[string][]string)
+	for _, w := range l.workflows {
+		if _, ok := steps[w.name]; ok {
+			continue // a duplicate, reported already
+		}
+		for _, n := range w.steps {
+			steps[w.name] = append(steps[w.name], n.Value)
+		}
+	}
+	for _, w := range l.workflows {
+		l.file = w.file
+		for _, n := range w.steps {
+			if l.names[n.Value] == "" {
+				msg := fmt.Sprintf("workflow %q: step %q is not a command", w.name, n.Value)
+				if guess := closest(n.Value, slices.Sorted(maps.Keys(l.names))); guess != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", guess)
+				}
+				l.report(n, Error, "%s", msg)
+			}
+		}
+		if reaches(steps, w.name, w.name, make(map[string]bool)) {
+			l.report(w.key, Error, "workflow %q runs itself through its steps", w.name)
+		}
+	}
+}
+
+// reaches reports whether following the steps of from leads to target.
+func reaches(steps map[string][]string, from, target string, seen map[string]bool) bool {
+	for _, s := range steps[from] {
+		if s == target {
+			return true
+		}
+		if !seen[s] {
+			seen[s] = true
+			if reaches(steps, s, target, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// closest returns the known key within edit distance 2 of s, if any.
+func closest(s string, known []string) string {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if d := strdist.Distance(s, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// Sort orders diagnostics by file, then position, keeping the file order given.
+func Sort(diags []Diagnostic, files []string) {
+	rank := make(map[string]int, len(files))
+	for i, f := range files {
+		rank[f] = i
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.File != b.File {
+			return rank[a.File] < rank[b.File]
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
@@ -0,0 +1,80 @@
+// Package search filters commands the way the TUI search bar does.
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"tb/internal/config"
+
+	"github.com/sahilm/fuzzy"
+)
+
+// Filter returns the commands matching query. #tag tokens in the query
+// narrow the list first; the remaining words are fuzzy-matched against name
+// and description, best match first. Equally good matches are ordered by
+// their frecency score.
+func Filter(commands []config.Command, query string, frecency map[string]float64) []config.Command {
+	tags, query := splitTagQuery(query)
+	pool := commands
+	if len(tags) > 0 {
+		pool = nil
+		for _, cmd := range commands {
+			if hasAllTags(cmd, tags) {
+				pool = append(pool, cmd)
+			}
+		}
+	}
+
+	if query == "" {
+		return pool
+	}
+
+	source := commandSource(pool)
+	matches := fuzzy.FindFrom(query, source)
+	// Break ties between equally good matches by frecency.
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return frecency[pool[matches[i].Index].Name] > frecency[pool[matches[j].Index].Name]
+	})
+	result := make([]config.Command, len(matches))
+	for i, match := range matches {
+		result[i] = pool[match.Index]
+	}
+	return result
+}
+
+// splitTagQuery separates #tag tokens from the rest of a search query.
+func splitTagQuery(input string) (tags []string, query string) {
+	var words []string
+	for _, field := range strings.Fields(input) {
+		if len(field) > 1 && strings.HasPrefix(field, "#") {
+			tags = append(tags, field[1:])
+		} else {
+			words = append(words, field)
+		}
+	}
+	return tags, strings.Join(words, " ")
+}
+
+func hasAllTags(cmd config.Command, tags []string) bool {
+	for _, tag := range tags {
+		if !cmd.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// commandSource adapts []config.Command for sahilm/fuzzy.
+type commandSource []config.Command
+
+func (s commandSource) String(i int) string {
+	return s[i].Name + " " + s[i].Description
+}
+
+func (s commandSource) Len() int {
+	return len(s)
+}
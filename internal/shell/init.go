@@ -75,7 +75,8 @@ tb() {
 
 const fishScript = `# tb shell integration (fish)
 function __tb_widget
-  set -l selected (command tb)
+  # string collect keeps a multi-line command as one string.
+  set -l selected (command tb | string collect)
   if test -n "$selected"
     commandline -r -- $selected
   end
@@ -89,7 +90,7 @@ function tb --wraps=tb
     command tb $argv
     return
   end
-  set -l selected (command tb)
+  set -l selected (command tb | string collect)
   if test -n "$selected"
     commandline -r -- $selected
     commandline -f repaint
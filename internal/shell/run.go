@@ -0,0 +1,38 @@
+package shell
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// outputTimeout bounds how long a placeholder source may run.
+const outputTimeout = 10 * time.Second
+
+// OutputLines runs script with the system shell and returns its non-empty output lines.
+func OutputLines(script string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), outputTimeout)
+	defer cancel()
+
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", script)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", script)
+	}
+	out, err := c.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,370 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"tb/internal/config"
+	"tb/internal/fsutil"
+	"tb/internal/importer"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Bulk operations on the marked commands. Moving to a category uses the
+// category dialog instead.
+const (
+	bulkDelete = iota
+	bulkTag
+	bulkExport
+)
+
+// defaultExportFile is offered as the export destination.
+const defaultExportFile = "tb-export.yaml"
+
+// targets returns the commands a bulk operation applies to: the marked
+// ones, in config order, or the highlighted one when nothing is marked.
+func (m Model) targets() []config.Command {
+	if len(m.marked) == 0 {
+		if len(m.filtered) == 0 {
+			return nil
+		}
+		return []config.Command{m.filtered[m.cursor]}
+	}
+	var cmds []config.Command
+	for _, cmd := range m.commands {
+		if m.marked[cmd.Name] {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// toggleMark marks or unmarks the highlighted command and moves down.
+func (m Model) toggleMark() Model {
+	name := m.filtered[m.cursor].Name
+	if m.marked[name] {
+		delete(m.marked, name)
+	} else {
+		if m.marked == nil {
+			m.marked = make(map[string]bool)
+		}
+		m.marked[name] = true
+	}
+	if m.cursor < len(m.filtered)-1 {
+		m.cursor++
+		m = m.adjustScroll()
+	}
+	return m
+}
+
+// toggleMarkAll marks every listed command, or clears them if all of them
+// are marked already.
+func (m Model) toggleMarkAll() Model {
+	all := len(m.filtered) > 0
+	for _, cmd := range m.filtered {
+		all = all && m.marked[cmd.Name]
+	}
+	if m.marked == nil {
+		m.marked = make(map[string]bool)
+	}
+	for _, cmd := range m.filtered {
+		if all {
+			delete(m.marked, cmd.Name)
+		} else {
+			m.marked[cmd.Name] = true
+		}
+	}
+	return m
+}
+
+// initBulk opens the dialog for op on the marked commands.
+func (m Model) initBulk(op int) (Model, tea.Cmd) {
+	if len(m.targets()) == 0 {
+		return m, nil
+	}
+	m.mode = modeBulk
+	m.bulkOp = op
+	m.bulkErr = ""
+	m.bulkInput = newFormInput()
+	switch op {
+	case bulkTag:
+		m.bulkInput.Placeholder = "docker, cleanup; -old removes old"
+	case bulkExport:
+		m.bulkInput.SetValue(defaultExportFile)
+	}
+	m.bulkInput.Focus()
+	return m, textinput.Blink
+}
+
+func (m Model) handleBulkKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, keys.ClearEsc) {
+		m.mode = modeBrowse
+		return m, nil
+	}
+	if m.bulkOp == bulkDelete {
+		switch msg.String() {
+		case "y":
+			return m.bulkDeleteMarked(), nil
+		case "n":
+			m.mode = modeBrowse
+		}
+		return m, nil
+	}
+	if key.Matches(msg, keys.Select) {
+		switch m.bulkOp {
+		case bulkTag:
+			return m.bulkTagMarked(), nil
+		case bulkExport:
+			return m.bulkExportMarked(), nil
+		}
+	}
+	var cmd tea.Cmd
+	m.bulkInput, cmd = m.bulkInput.Update(msg)
+	m.bulkErr = ""
+	return m, cmd
+}
+
+// finishBulk records a bulk step, clears the marks and returns to the list.
+func (m Model) finishBulk(commands []config.Command, s step) Model {
+	m = m.recordStep(s)
+	m.commands = commands
+	m.marked = nil
+	m.mode = modeBrowse
+	return m.refreshAfterMutation()
+}
+
+func (m Model) bulkDeleteMarked() Model {
+	targets := m.targets()
+	commands := slices.Clone(m.commands)
+	// Delete from the end so the recorded indices put everything back in
+	// place when undo reinserts them first to last.
+	var changes []change
+	for i := len(commands) - 1; i >= 0; i-- {
+		if slices.ContainsFunc(targets, func(c config.Command) bool { return c.Name == commands[i].Name }) {
+			old := commands[i]
+			changes = append(changes, change{old: &old, index: i})
+			commands = slices.Delete(commands, i, i+1)
+		}
+	}
+	if err := m.cfg.Save(commands); err != nil {
+		m.statusMsg = saveError("Delete", err)
+		m.mode = modeBrowse
+		return m
+	}
+	n := pluralize(len(targets), "command")
+	m = m.finishBulk(commands, step{changes: changes, label: "delete of " + n})
+	m.statusMsg = fmt.Sprintf("Deleted %s (%s to undo)", n, keys.Undo.Help().Key)
+	if err := m.cfg.MoveToTrash(targets...); err != nil {
+		m.statusMsg = fmt.Sprintf("Deleted %s, but could not keep them in the trash: %v", n, err)
+	}
+	return m
+}
+
+// updateTargets applies fn to each target command and saves the result as
+// one undoable step. fn reports whether it changed the command.
+func (m Model) updateTargets(action, label string, fn func(*config.Command) bool) (Model, int, error) {
+	targets := m.targets()
+	commands := slices.Clone(m.commands)
+	var changes []change
+	for _, t := range targets {
+		i := config.Find(commands, t.Name)
+		if i < 0 {
+			continue
+		}
+		old := commands[i]
+		updated := old
+		updated.Tags = slices.Clone(old.Tags)
+		if !fn(&updated) {
+			continue
+		}
+		commands[i] = updated
+		changes = append(changes, change{old: &old, new: &updated, index: i})
+	}
+	if len(changes) == 0 {
+		m.marked = nil
+		m.mode = modeBrowse
+		return m, 0, nil
+	}
+	if err := m.cfg.Save(commands); err != nil {
+		return m, 0, errors.New(saveError(action, err))
+	}
+	m = m.finishBulk(commands, step{changes: changes, label: label + " of " + pluralize(len(changes), "command")})
+	return m, len(changes), nil
+}
+
+func (m Model) bulkTagMarked() Model {
+	var add, remove []string
+	for _, tag := range config.ParseTags(m.bulkInput.Value()) {
+		if rest, ok := strings.CutPrefix(tag, "-"); ok {
+			remove = append(remove, config.ParseTags(rest)...)
+		} else {
+			add = append(add, tag)
+		}
+	}
+	if len(add)+len(remove) == 0 {
+		m.bulkErr = "Enter tags to add, or -tag to remove"
+		return m
+	}
+
+	m, n, err := m.updateTargets("Tag", "tagging", func(cmd *config.Command) bool {
+		changed := false
+		for _, tag := range remove {
+			if cmd.HasTag(tag) {
+				cmd.Tags = slices.DeleteFunc(cmd.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+				changed = true
+			}
+		}
+		for _, tag := range add {
+			if !cmd.HasTag(tag) {
+				cmd.Tags = append(cmd.Tags, tag)
+				changed = true
+			}
+		}
+		return changed
+	})
+	if err != nil {
+		m.bulkErr = err.Error()
+		return m
+	}
+	m.statusMsg = fmt.Sprintf("Tagged %s", pluralize(n, "command"))
+	if n == 0 {
+		m.statusMsg = "Tags already up to date"
+	}
+	return m
+}
+
+// moveToCategory sets the category of the marked commands to the one typed
+// in the category dialog; an empty one clears it.
+func (m Model) moveToCategory() (Model, tea.Cmd) {
+	to := strings.TrimSpace(m.catInput.Value())
+	m, n, err := m.updateTargets("Move", "move", func(cmd *config.Command) bool {
+		if cmd.Category == to {
+			return false
+		}
+		cmd.Category = to
+		return true
+	})
+	if err != nil {
+		m.catErr = err.Error()
+		return m, nil
+	}
+	switch {
+	case n == 0:
+		m.statusMsg = "Nothing to move"
+	case to == "":
+		m.statusMsg = fmt.Sprintf("Cleared the category of %s", pluralize(n, "command"))
+	default:
+		m.statusMsg = fmt.Sprintf("Moved %s to %s (%s to undo)", pluralize(n, "command"), to, keys.Undo.Help().Key)
+	}
+	return m, nil
+}
+
+// bulkExportMarked writes the marked commands to a new file, as a navi
+// cheat for .cheat, a pet snippet file for .toml, and a tb command file
+// otherwise. A tb command file also gets the steps of marked workflows.
+func (m Model) bulkExportMarked() Model {
+	path := strings.TrimSpace(m.bulkInput.Value())
+	if path == "" {
+		m.bulkErr = "File name is required"
+		return m
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	targets := m.targets()
+	// navi and pet have no workflows; they get the joined command.
+	resolved := make([]config.Command, len(targets))
+	for i, cmd := range targets {
+		resolved[i], _ = config.Resolve(m.commands, cmd)
+	}
+
+	var data []byte
+	var note string
+	var err error
+	switch filepath.Ext(path) {
+	case ".cheat":
+		var b bytes.Buffer
+		err = importer.WriteNavi(&b, resolved)
+		data = b.Bytes()
+	case ".toml":
+		var b bytes.Buffer
+		err = importer.WritePet(&b, resolved)
+		data = b.Bytes()
+	default:
+		withSteps := config.WithSteps(m.commands, targets)
+		if extra := len(withSteps) - len(targets); extra > 0 {
+			note = fmt.Sprintf(" with the %s they run", pluralize(extra, "step"))
+		}
+		data, err = config.EncodePack(withSteps)
+	}
+	if err == nil {
+		err = fsutil.WriteNewFile(path, data, 0644)
+	}
+	if errors.Is(err, os.ErrExist) {
+		m.bulkErr = path + " already exists, choose another file"
+		return m
+	}
+	if err != nil {
+		m.bulkErr = fmt.Sprintf("Export failed: %v", err)
+		return m
+	}
+	m.marked = nil
+	m.mode = modeBrowse
+	m.statusMsg = fmt.Sprintf("Exported %s%s to %s", pluralize(len(targets), "command"), note, path)
+	return m
+}
+
+// renderMarked is the status line while commands are marked.
+func (m Model) renderMarked() string {
+	hint := func(b key.Binding) string {
+		return helpSepStyle.Render(" · ") + helpKeyStyle.Render(b.Help().Key) + helpDescStyle.Render(" "+b.Help().Desc)
+	}
+	return statusMsgStyle.Render(fmt.Sprintf(" %d marked", len(m.marked))) +
+		hint(keys.Delete) + hint(keys.Move) + hint(keys.Tag) + hint(keys.Export) +
+		helpSepStyle.Render(" · ") + helpKeyStyle.Render(keys.ClearEsc.Help().Key) + helpDescStyle.Render(" unmark")
+}
+
+func (m Model) renderBulk(areaWidth, areaHeight int) string {
+	n := pluralize(len(m.targets()), "command")
+	if m.bulkOp == bulkDelete {
+		title := deleteConfirmStyle.Render(fmt.Sprintf("Delete %s?", n))
+		options := helpKeyStyle.Render("y") + helpDescStyle.Render(" yes") +
+			helpSepStyle.Render("  ") +
+			helpKeyStyle.Render("n") + helpDescStyle.Render(" no") +
+			helpSepStyle.Render("  ") +
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" cancel")
+		box := deleteBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Center, title, "", options))
+		return lipgloss.Place(areaWidth, areaHeight, lipgloss.Center, lipgloss.Center, box)
+	}
+
+	title, note := fmt.Sprintf(" Tag %s ", n), "Comma separated; prefix a tag with - to remove it"
+	if m.bulkOp == bulkExport {
+		title, note = fmt.Sprintf(" Export %s ", n), ".cheat writes navi, .toml writes pet, anything else a tb command file"
+	}
+	rows := []string{formHeaderStyle.Render(title), "", m.bulkInput.View(),
+		formUnderlineStyle.Render("  " + strings.Repeat("─", 30)), ""}
+	if m.bulkErr != "" {
+		rows = append(rows, formErrStyle.Render("  ✗ "+m.bulkErr))
+	} else {
+		width := max(20, min(50, areaWidth-12))
+		rows = append(rows, helpDescStyle.Width(width).Render("  "+note))
+	}
+	options := helpKeyStyle.Render("enter") + helpDescStyle.Render(" apply") +
+		helpSepStyle.Render("  ") +
+		helpKeyStyle.Render("esc") + helpDescStyle.Render(" cancel")
+	rows = append(rows, "", options)
+
+	box := formContainerStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+	return lipgloss.Place(areaWidth, areaHeight, lipgloss.Center, lipgloss.Center, box)
+}
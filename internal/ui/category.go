@@ -0,0 +1,352 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"tb/internal/config"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+// categoryInput is the input the category dropdown completes: the rename
+// dialog's, or the form's Category field.
+func (m *Model) categoryInput() *textinput.Model {
+	if m.mode == modeCategory {
+		return &m.catInput
+	}
+	return &m.formFields[fieldCat].input
+}
+
+// categoryFocused reports whether a category input has the focus.
+func (m Model) categoryFocused() bool {
+	return m.mode == modeCategory || (m.mode == modeForm && m.formFocused == fieldCat)
+}
+
+// filterCategories recomputes the category dropdown from the typed value.
+// Nothing is highlighted while the field is empty or already names an
+// existing category, so tab only completes when there is something to add.
+func (m Model) filterCategories() Model {
+	query := strings.TrimSpace(m.categoryInput().Value())
+	m.catCursor = 0
+	if query == "" {
+		m.catMatches = m.formCats
+		m.catCursor = -1
+	} else {
+		m.catMatches = m.catMatches[:0:0]
+		for _, match := range fuzzy.Find(query, m.formCats) {
+			m.catMatches = append(m.catMatches, match.Str)
+		}
+		if slices.Contains(m.formCats, query) {
+			m.catCursor = -1
+		}
+	}
+	return m.syncCategoryHint()
+}
+
+// syncCategoryHint shows the rest of the highlighted category inline after
+// the cursor, when the typed text is a prefix of it.
+func (m Model) syncCategoryHint() Model {
+	var hint []string
+	if s := m.categorySuggestion(); s != "" {
+		hint = []string{s}
+	}
+	m.categoryInput().SetSuggestions(hint)
+	return m
+}
+
+// categorySuggestion returns the highlighted category, or "" if there is
+// none or it is what was typed already.
+func (m Model) categorySuggestion() string {
+	if !m.categoryFocused() || m.catCursor < 0 || m.catCursor >= len(m.catMatches) {
+		return ""
+	}
+	if s := m.catMatches[m.catCursor]; s != m.categoryInput().Value() {
+		return s
+	}
+	return ""
+}
+
+// handleCategoryChoiceKeys moves through and completes from the dropdown.
+// It reports false for keys it doesn't handle.
+func (m Model) handleCategoryChoiceKeys(msg tea.KeyMsg) (Model, bool) {
+	switch {
+	case key.Matches(msg, keys.FormTab) && m.categorySuggestion() != "":
+		// Tab completes the highlighted category; the next tab moves on.
+		m.categoryInput().SetValue(m.categorySuggestion())
+		m.categoryInput().CursorEnd()
+		return m.filterCategories(), true
+	case key.Matches(msg, keys.ChoiceUp):
+		if m.catCursor >= 0 {
+			m.catCursor--
+		}
+		return m.syncCategoryHint(), true
+	case key.Matches(msg, keys.ChoiceDown):
+		if m.catCursor < len(m.catMatches)-1 {
+			m.catCursor++
+		}
+		return m.syncCategoryHint(), true
+	}
+	return m, false
+}
+
+// similarCategory returns the existing category the typed one looks like a
+// variant of, if any.
+func (m Model) similarCategory() string {
+	return config.SimilarCategory(m.formCats, strings.TrimSpace(m.categoryInput().Value()))
+}
+
+// renderCategoryChoices draws the dropdown under the focused category input.
+func (m Model) renderCategoryChoices(width int) string {
+	if len(m.catMatches) == 0 {
+		return ""
+	}
+	// Scroll the window so the highlighted category stays visible.
+	start := max(0, m.catCursor-maxVisibleChoices+1)
+	end := min(start+maxVisibleChoices, len(m.catMatches))
+	var lines []string
+	for i := start; i < end; i++ {
+		if i == m.catCursor {
+			lines = append(lines, "  "+cursorStyle.Render("> ")+
+				selectedItemStyle.MaxWidth(width).Render(m.catMatches[i]))
+		} else {
+			lines = append(lines, "    "+normalItemStyle.MaxWidth(width).Render(m.catMatches[i]))
+		}
+	}
+	if len(m.catMatches) > maxVisibleChoices {
+		lines = append(lines, scrollIndicatorStyle.Render(
+			fmt.Sprintf("    %d more", len(m.catMatches)-(end-start))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderCategoryWarning notes when the typed category is probably a typo or
+// a differently-cased copy of an existing one.
+func (m Model) renderCategoryWarning() string {
+	similar := m.similarCategory()
+	if similar == "" {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(clrWarning).Render(
+		fmt.Sprintf("  ! close to existing %q", similar))
+}
+
+// ── Rename / merge ──────────────────────────────────────────────────
+
+// initRenameCategory opens the rename dialog for the active category tab,
+// or the highlighted command's category on the other tabs.
+func (m Model) initRenameCategory() (Model, tea.Cmd) {
+	cat := m.currentCategory()
+	if cat == "" && len(m.filtered) > 0 {
+		cat = m.filtered[m.cursor].Category
+	}
+	if cat == "" {
+		m.statusMsg = "No category to rename"
+		return m, nil
+	}
+
+	m = m.openCategoryDialog(cat)
+	m.catRename = cat
+	m.formCats = slices.DeleteFunc(m.cfg.Categories(), func(c string) bool { return config.InCategory(c, cat) })
+	return m.filterCategories(), textinput.Blink
+}
+
+// initMoveCategory opens the category dialog to move the marked commands,
+// or the highlighted one, to another category.
+func (m Model) initMoveCategory() (Model, tea.Cmd) {
+	targets := m.targets()
+	if len(targets) == 0 {
+		return m, nil
+	}
+	// Start from their category if they share one.
+	cat := targets[0].Category
+	for _, t := range targets {
+		if t.Category != cat {
+			cat = ""
+		}
+	}
+	m = m.openCategoryDialog(cat)
+	m.catMove = true
+	m.formCats = m.cfg.Categories()
+	return m.filterCategories(), textinput.Blink
+}
+
+// openCategoryDialog switches to the category dialog with value typed in.
+func (m Model) openCategoryDialog(value string) Model {
+	m.mode = modeCategory
+	m.catRename = ""
+	m.catMove = false
+	m.catErr = ""
+	m.catInput = newFormInput()
+	m.catInput.ShowSuggestions = true
+	m.catInput.CompletionStyle = lipgloss.NewStyle().Foreground(clrTextMuted)
+	m.catInput.SetValue(value)
+	m.catInput.Focus()
+	return m
+}
+
+func (m Model) handleCategoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.ClearEsc):
+		m.mode = modeBrowse
+		return m, nil
+	case key.Matches(msg, keys.Select) && m.catMove:
+		return m.moveToCategory()
+	case key.Matches(msg, keys.Select):
+		return m.renameCategory()
+	}
+	if m, ok := m.handleCategoryChoiceKeys(msg); ok {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	before := m.catInput.Value()
+	m.catInput, cmd = m.catInput.Update(msg)
+	if m.catInput.Value() != before {
+		m.catErr = ""
+		m = m.filterCategories()
+	}
+	return m, cmd
+}
+
+// renameCategory moves every command in the category being renamed, and in
+// the categories nested below it, to the typed one, merging the two if it
+// already exists, with a single save.
+func (m Model) renameCategory() (Model, tea.Cmd) {
+	from, to := m.catRename, strings.TrimSpace(m.catInput.Value())
+	switch {
+	case to == "":
+		m.catErr = "Category name is required"
+		return m, nil
+	case to == from:
+		m.mode = modeBrowse
+		return m, nil
+	case config.InCategory(to, from):
+		m.catErr = fmt.Sprintf("Can't move %s inside itself", from)
+		return m, nil
+	}
+	merge := slices.Contains(m.formCats, to)
+
+	commands := slices.Clone(m.commands)
+	var changes []change
+	for i := range commands {
+		if !config.InCategory(commands[i].Category, from) {
+			continue
+		}
+		old := commands[i]
+		commands[i].Category = to + strings.TrimPrefix(old.Category, from)
+		updated := commands[i]
+		changes = append(changes, change{old: &old, new: &updated, index: i})
+	}
+	if err := m.cfg.Save(commands); err != nil {
+		m.catErr = saveError("Rename", err)
+		return m, nil
+	}
+
+	s := step{changes: changes, label: "rename of " + from}
+	if merge {
+		s.label = "merge of " + from
+	}
+	renamed, err := m.cfg.RenameCategoryInfo(from, to)
+	if renamed {
+		s.renamed = [2]string{from, to}
+	}
+	m = m.recordStep(s)
+	m.commands = commands
+	m.mode = modeBrowse
+
+	// Follow the category to its new tab.
+	tab := m.currentTab()
+	if config.InCategory(tab, from) {
+		tab = to + strings.TrimPrefix(tab, from)
+	}
+	m = m.refreshTabs(tab)
+
+	verb := fmt.Sprintf("Renamed %s to %s", from, to)
+	if merge {
+		verb = fmt.Sprintf("Merged %s into %s", from, to)
+	}
+	m.statusMsg = fmt.Sprintf("%s (%s, %s to undo)", verb, pluralize(len(changes), "command"), keys.Undo.Help().Key)
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("%s, but could not rename its categories entry: %v", verb, err)
+	}
+	return m, nil
+}
+
+// countCategory returns how many commands are in cat or nested below it.
+func (m Model) countCategory(cat string) int {
+	n := 0
+	for _, cmd := range m.commands {
+		if config.InCategory(cmd.Category, cat) {
+			n++
+		}
+	}
+	return n
+}
+
+func (m Model) renderRenameCategory(areaWidth, areaHeight int) string {
+	width := max(20, min(50, areaWidth-12))
+	to := strings.TrimSpace(m.catInput.Value())
+
+	title := formHeaderStyle.Render(fmt.Sprintf(" Rename %s ", m.cfg.CategoryLabel(m.catRename)))
+	if m.catMove {
+		title = formHeaderStyle.Render(fmt.Sprintf(" Move %s ", pluralize(len(m.targets()), "command")))
+	}
+	rows := []string{title, "", m.catInput.View(),
+		formUnderlineStyle.Render("  " + strings.Repeat("─", 30))}
+	if choices := m.renderCategoryChoices(width); choices != "" {
+		rows = append(rows, choices)
+	}
+
+	n := pluralize(m.countCategory(m.catRename), "command")
+	if m.catMove {
+		n = pluralize(len(m.targets()), "command")
+	}
+	var summary string
+	switch {
+	case m.catErr != "":
+		summary = formErrStyle.Render("  ✗ " + m.catErr)
+	case m.catMove && to == "":
+		summary = helpDescStyle.Render(fmt.Sprintf("  Clears the category of %s", n))
+	case m.catMove:
+		summary = helpDescStyle.Render(fmt.Sprintf("  Moves %s to %s", n, to))
+		if warning := m.renderCategoryWarning(); warning != "" {
+			summary += "\n" + warning
+		}
+	case to == "" || to == m.catRename:
+		summary = helpDescStyle.Render(fmt.Sprintf("  %s in %s", n, m.catRename))
+	case slices.Contains(m.formCats, to):
+		summary = lipgloss.NewStyle().Foreground(clrWarning).Render(
+			fmt.Sprintf("  Merges %s into %s (%s)", n, to, pluralize(m.countCategory(to), "command")))
+	default:
+		summary = helpDescStyle.Render(fmt.Sprintf("  Moves %s to %s", n, to))
+		if warning := m.renderCategoryWarning(); warning != "" {
+			summary += "\n" + warning
+		}
+	}
+	rows = append(rows, "", summary)
+
+	options := helpKeyStyle.Render("enter") + helpDescStyle.Render(" apply") +
+		helpSepStyle.Render("  ") +
+		helpKeyStyle.Render("tab") + helpDescStyle.Render(" complete") +
+		helpSepStyle.Render("  ") +
+		helpKeyStyle.Render("esc") + helpDescStyle.Render(" cancel")
+	rows = append(rows, "", options)
+
+	box := formContainerStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+	return lipgloss.Place(areaWidth, areaHeight,
+		lipgloss.Center, lipgloss.Center, box)
+}
+
+// pluralize formats n with noun, adding an s unless n is 1.
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"tb/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// editorDoneMsg reports that the external editor exited.
+type editorDoneMsg struct {
+	path string // temp file holding the command
+	name string // name of the command being edited, before the edit
+	err  error
+}
+
+// editorCommand builds the command for $VISUAL or $EDITOR, which may
+// include arguments such as "code --wait".
+func editorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	args := strings.Fields(editor)
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// editorHeader explains the temp file; problem, if any, is shown above it.
+func editorHeader(cmd config.Command, problem string) string {
+	var b strings.Builder
+	if problem != "" {
+		for _, line := range strings.Split(problem, "\n") {
+			fmt.Fprintf(&b, "# ERROR: %s\n", line)
+		}
+		b.WriteString("#\n")
+	}
+	if cmd.Origin != "" {
+		fmt.Fprintf(&b, "# Editing %q from %s.\n", cmd.Name, cmd.Origin)
+	} else {
+		fmt.Fprintf(&b, "# Editing %q.\n", cmd.Name)
+	}
+	b.WriteString("# Save and quit to apply it; delete everything to cancel.\n")
+	return b.String()
+}
+
+// openEditor writes content to the temp file at path, or a new one if path
+// is empty, and suspends the TUI while the editor runs.
+func openEditor(path, name, content string) tea.Cmd {
+	if path == "" {
+		f, err := os.CreateTemp("", "tb-*.yaml")
+		if err != nil {
+			return func() tea.Msg { return editorDoneMsg{name: name, err: err} }
+		}
+		path = f.Name()
+		f.Close()
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return func() tea.Msg { return editorDoneMsg{path: path, name: name, err: err} }
+	}
+	return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+		return editorDoneMsg{path: path, name: name, err: err}
+	})
+}
+
+// editInEditor opens the highlighted command as a YAML document in the
+// user's editor.
+func (m Model) editInEditor() (Model, tea.Cmd) {
+	cmd := m.filtered[m.cursor]
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cmd); err != nil {
+		m.statusMsg = fmt.Sprintf("Edit failed: %v", err)
+		return m, nil
+	}
+	return m, openEditor("", cmd.Name, editorHeader(cmd, "")+buf.String())
+}
+
+// handleEditorDone applies the edited command, or reopens the editor with
+// the problem noted at the top if it can't be applied.
+func (m Model) handleEditorDone(msg editorDoneMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		os.Remove(msg.path)
+		m.statusMsg = fmt.Sprintf("Editor failed: %v", msg.err)
+		return m, nil
+	}
+	i := config.Find(m.commands, msg.name)
+	if i < 0 {
+		os.Remove(msg.path)
+		m.statusMsg = msg.name + " no longer exists, edit discarded"
+		return m, nil
+	}
+	old := m.commands[i]
+
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		os.Remove(msg.path)
+		m.statusMsg = fmt.Sprintf("Edit failed: %v", err)
+		return m, nil
+	}
+	body := stripHeader(string(data))
+	if strings.TrimSpace(body) == "" {
+		os.Remove(msg.path)
+		m.statusMsg = "Edit cancelled"
+		return m, nil
+	}
+
+	updated, err := parseEdited(body, old, m.commands, i)
+	if err != nil {
+		return m, openEditor(msg.path, msg.name, editorHeader(old, capitalize(err.Error()))+body)
+	}
+	os.Remove(msg.path)
+	if reflect.DeepEqual(updated, old) {
+		m.statusMsg = "No changes"
+		return m, nil
+	}
+
+	commands := slices.Clone(m.commands)
+	commands[i] = updated
+	renamed := config.RenameStep(commands, old.Name, updated.Name)
+	if err := m.cfg.Save(commands); err != nil {
+		m.statusMsg = saveError("Save", err)
+		return m, nil
+	}
+	m = m.recordStep(step{changes: withRenamedSteps(change{old: &old, new: &updated, index: i}, m.commands, commands, renamed)})
+	m.commands = commands
+	m = m.refreshAfterMutation()
+	if j := config.Find(m.filtered, updated.Name); j >= 0 {
+		m.cursor = j
+		m = m.adjustScroll()
+	}
+	m.statusMsg = "Updated " + updated.Name
+	return m, nil
+}
+
+// parseEdited decodes the edited document and checks it the way saveForm
+// does. Unknown keys are rejected so typos don't silently drop a field.
+func parseEdited(body string, old config.Command, commands []config.Command, i int) (config.Command, error) {
+	var cmd config.Command
+	dec := yaml.NewDecoder(strings.NewReader(body))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cmd); err != nil {
+		return cmd, err
+	}
+	cmd.Name = strings.TrimSpace(cmd.Name)
+	cmd.Description = strings.TrimSpace(cmd.Description)
+	cmd.Command = strings.TrimSpace(cmd.Command)
+	cmd.Category = strings.TrimSpace(cmd.Category)
+	cmd.Origin = old.Origin
+	if err := config.Validate(commands, cmd, i); err != nil {
+		return cmd, err
+	}
+	// Catch broken steps here, while the editor can still reopen.
+	after := slices.Clone(commands)
+	after[i] = cmd
+	config.RenameStep(after, old.Name, cmd.Name)
+	return cmd, config.CheckSteps(commands, after)
+}
+
+// stripHeader removes the leading comment block written by editorHeader.
+func stripHeader(s string) string {
+	for strings.HasPrefix(s, "#") {
+		_, rest, found := strings.Cut(s, "\n")
+		if !found {
+			return ""
+		}
+		s = rest
+	}
+	return s
+}
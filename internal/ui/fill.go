@@ -0,0 +1,295 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tb/internal/config"
+	"tb/internal/shell"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+// maxVisibleChoices caps the pick list shown under a sourced placeholder.
+const maxVisibleChoices = 5
+
+// fillField is one placeholder input in the fill-in form.
+type fillField struct {
+	name    string
+	input   textinput.Model
+	source  string   // shell command producing choices; empty for free text
+	loading bool     // source is still running
+	choices []string // output lines of source
+	matches []string // choices fuzzy-filtered by the input value
+	cursor  int      // highlighted entry in matches, -1 for the typed text
+	err     string   // source failure, if any
+}
+
+// value returns the highlighted choice, or the typed text.
+func (f fillField) value() string {
+	if f.cursor < 0 || len(f.matches) == 0 {
+		return f.input.Value()
+	}
+	return f.matches[f.cursor]
+}
+
+// filter recomputes matches from the current input value. Typed text stays
+// the value until a choice is highlighted, so it can be used as is even
+// when it also matches some of the choices.
+func (f fillField) filter() fillField {
+	f.cursor = -1
+	query := f.input.Value()
+	if query == "" {
+		f.cursor = 0
+		f.matches = f.choices
+		return f
+	}
+	matches := fuzzy.Find(query, f.choices)
+	f.matches = make([]string, len(matches))
+	for i, match := range matches {
+		f.matches[i] = match.Str
+	}
+	return f
+}
+
+// choicesMsg carries the output of a placeholder source back to the model.
+type choicesMsg struct {
+	fillID  int
+	field   int
+	choices []string
+	err     error
+}
+
+func loadChoices(fillID, field int, source string) tea.Cmd {
+	return func() tea.Msg {
+		lines, err := shell.OutputLines(source)
+		return choicesMsg{fillID: fillID, field: field, choices: lines, err: err}
+	}
+}
+
+// choose selects cmd, opening the placeholder form first if the command has
+// any. A workflow is selected as the && chain of its steps.
+func (m Model) choose(cmd config.Command) (Model, tea.Cmd) {
+	cmd, err := config.Resolve(m.commands, cmd)
+	if err != nil {
+		m.statusMsg = capitalize(err.Error())
+		return m, nil
+	}
+	names := cmd.PlaceholderNames()
+	if len(names) == 0 {
+		_ = m.history.Record(cmd.Name)
+		m.selected = &cmd
+		return m, tea.Quit
+	}
+	return m.initFillForm(cmd, names)
+}
+
+func (m Model) initFillForm(cmd config.Command, names []string) (Model, tea.Cmd) {
+	m.mode = modeFill
+	m.fillID++
+	m.fillCmd = cmd
+	m.fillErr = ""
+	m.fillFields = make([]fillField, len(names))
+
+	cmds := []tea.Cmd{textinput.Blink}
+	for i, name := range names {
+		f := fillField{name: name, input: newFormInput()}
+		f.input.SetValue(cmd.Placeholders[name].Default)
+		if src := cmd.Placeholders[name].Source; src != "" {
+			f.source = src
+			f.loading = true
+			f.input.Placeholder = "type to filter..."
+			cmds = append(cmds, loadChoices(m.fillID, i, src))
+		}
+		m.fillFields[i] = f
+	}
+	m.fillFocused = 0
+	m.fillFields[0].input.Focus()
+	return m, tea.Batch(cmds...)
+}
+
+func (m Model) handleChoices(msg choicesMsg) Model {
+	// Ignore results for a form that has since been closed or replaced.
+	if m.mode != modeFill || msg.fillID != m.fillID {
+		return m
+	}
+	f := m.fillFields[msg.field]
+	f.loading = false
+	if msg.err != nil {
+		f.err = fmt.Sprintf("source failed: %v", msg.err)
+	} else {
+		f.choices = msg.choices
+	}
+	m.fillFields[msg.field] = f.filter()
+	return m
+}
+
+// fillValues returns the placeholder values chosen so far, skipping empty ones.
+func (m Model) fillValues() map[string]string {
+	values := make(map[string]string, len(m.fillFields))
+	for _, f := range m.fillFields {
+		if v := f.value(); v != "" {
+			values[f.name] = v
+		}
+	}
+	return values
+}
+
+func (m Model) focusFill(i int) Model {
+	m.fillFields[m.fillFocused].input.Blur()
+	m.fillFocused = i
+	m.fillFields[i].input.Focus()
+	return m
+}
+
+func (m Model) handleFillKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	n := len(m.fillFields)
+	f := &m.fillFields[m.fillFocused]
+	switch {
+	case key.Matches(msg, keys.ClearEsc):
+		m.mode = modeBrowse
+		return m, nil
+	case key.Matches(msg, keys.Select):
+		return m.submitFill()
+	case key.Matches(msg, keys.FormTab):
+		m = m.focusFill((m.fillFocused + 1) % n)
+		return m, textinput.Blink
+	case key.Matches(msg, keys.FormBackTab):
+		m = m.focusFill((m.fillFocused - 1 + n) % n)
+		return m, textinput.Blink
+	case key.Matches(msg, keys.ChoiceUp):
+		if f.cursor > 0 || f.cursor == 0 && f.input.Value() != "" {
+			f.cursor--
+		}
+		return m, nil
+	case key.Matches(msg, keys.ChoiceDown):
+		if f.cursor < len(f.matches)-1 {
+			f.cursor++
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	if f.source != "" {
+		*f = f.filter()
+	}
+	return m, cmd
+}
+
+func (m Model) submitFill() (Model, tea.Cmd) {
+	values := m.fillValues()
+	for i, f := range m.fillFields {
+		if _, ok := values[f.name]; !ok {
+			m.fillErr = fmt.Sprintf("Value for %q is required", f.name)
+			m = m.focusFill(i)
+			return m, textinput.Blink
+		}
+	}
+
+	cmd := m.fillCmd
+	cmd.Command = cmd.Fill(values)
+	_ = m.history.Record(cmd.Name)
+	m.selected = &cmd
+	return m, tea.Quit
+}
+
+// renderChoices draws the pick list for a sourced placeholder field.
+func (f fillField) renderChoices(width int) string {
+	dim := lipgloss.NewStyle().Foreground(clrTextSec)
+	switch {
+	case f.loading:
+		return dim.Render("    loading choices...")
+	case f.err != "":
+		return formErrStyle.Render("    " + f.err)
+	case len(f.matches) == 0:
+		return dim.Render("    no matches, using typed value")
+	}
+
+	var lines []string
+	if typed := f.input.Value(); typed != "" {
+		entry := fmt.Sprintf("use %q", typed)
+		if f.cursor < 0 {
+			lines = append(lines, "  "+cursorStyle.Render("> ")+selectedItemStyle.MaxWidth(width).Render(entry))
+		} else {
+			lines = append(lines, "    "+dim.MaxWidth(width).Render(entry))
+		}
+	}
+	// Scroll the window so the highlighted choice stays visible.
+	start := max(0, f.cursor-maxVisibleChoices+1)
+	end := min(start+maxVisibleChoices, len(f.matches))
+	for i := start; i < end; i++ {
+		if i == f.cursor {
+			lines = append(lines, "  "+cursorStyle.Render("> ")+
+				selectedItemStyle.MaxWidth(width).Render(f.matches[i]))
+		} else {
+			lines = append(lines, "    "+normalItemStyle.MaxWidth(width).Render(f.matches[i]))
+		}
+	}
+	if len(f.matches) > maxVisibleChoices {
+		lines = append(lines, scrollIndicatorStyle.Render(
+			fmt.Sprintf("    %d/%d", max(f.cursor, 0)+1, len(f.matches))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m Model) renderFill() string {
+	header := formHeaderStyle.Render(" " + m.fillCmd.Name + " ")
+
+	contentWidth := m.cardWidth()
+
+	var rows []string
+	for i, f := range m.fillFields {
+		labelText := "  " + f.name
+
+		var label string
+		if i == m.fillFocused {
+			label = formFocusedLabelStyle.Render(labelText)
+		} else {
+			label = formLabelStyle.Render(labelText)
+		}
+
+		input := f.input.View()
+
+		row := label + "\n" + input
+		if i == m.fillFocused {
+			underline := formUnderlineStyle.Render("  " + strings.Repeat("─", 30))
+			row += "\n" + underline
+			if f.source != "" {
+				row += "\n" + f.renderChoices(contentWidth)
+			}
+		}
+		rows = append(rows, row)
+	}
+	body := strings.Join(rows, "\n\n")
+
+	preview := formLabelStyle.Render("  Preview") + "\n" +
+		commandValueStyle.Width(contentWidth).PaddingLeft(2).Render(m.fillCmd.Fill(m.fillValues()))
+
+	var errLine string
+	if m.fillErr != "" {
+		errLine = "\n" + formErrStyle.Render("  ✗ "+m.fillErr)
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		header, "", body, "", preview, errLine)
+	card := formContainerStyle.Render(content)
+
+	helpLine := helpKeyStyle.Render("tab") + helpDescStyle.Render(" next") +
+		helpSepStyle.Render(" · ") +
+		helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" choose") +
+		helpSepStyle.Render(" · ") +
+		helpKeyStyle.Render("enter") + helpDescStyle.Render(" use command") +
+		helpSepStyle.Render(" · ") +
+		helpKeyStyle.Render("esc") + helpDescStyle.Render(" cancel")
+
+	cardWithHelp := lipgloss.JoinVertical(lipgloss.Center,
+		card, "", helpLine)
+
+	return lipgloss.Place(m.innerWidth(), m.innerHeight(),
+		lipgloss.Center, lipgloss.Center, cardWithHelp)
+}
@@ -1,12 +1,15 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"tb/internal/config"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -17,28 +20,120 @@ const (
 	fieldDesc
 	fieldCmd
 	fieldCat
+	fieldTags
 	numFields
 )
 
-var fieldLabels = [numFields]string{"Name (*)", "Description", "Command (*)", "Category"}
+var fieldLabels = [numFields]string{"Name (*)", "Description", "Command (*)", "Category", "Tags"}
+
+// fieldHeights gives the textarea height of the fields that may span lines.
+var fieldHeights = map[int]int{fieldDesc: 2, fieldCmd: 4}
+
+// formField is one input of the create/edit form: a single-line text input,
+// or a textarea for fields that may span several lines.
+type formField struct {
+	input     textinput.Model
+	area      textarea.Model
+	multiline bool
+}
+
+func newFormField(height, width int) formField {
+	if height == 0 {
+		return formField{input: newFormInput()}
+	}
+	ta := textarea.New()
+	ta.Prompt = "  "
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.SetHeight(height)
+	ta.SetWidth(width)
+	ta.FocusedStyle.Base = lipgloss.NewStyle()
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.FocusedStyle.Text = lipgloss.NewStyle().Foreground(clrTextPri)
+	ta.FocusedStyle.Placeholder = lipgloss.NewStyle().Foreground(clrTextSec)
+	ta.FocusedStyle.EndOfBuffer = lipgloss.NewStyle()
+	ta.BlurredStyle = ta.FocusedStyle
+	ta.Cursor.Style = lipgloss.NewStyle().Foreground(clrAccent)
+	return formField{area: ta, multiline: true}
+}
+
+func (f formField) Value() string {
+	if f.multiline {
+		return f.area.Value()
+	}
+	return f.input.Value()
+}
+
+func (f *formField) SetValue(s string) {
+	if f.multiline {
+		f.area.SetValue(s)
+	} else {
+		f.input.SetValue(s)
+	}
+}
+
+func (f *formField) Focus() {
+	if f.multiline {
+		f.area.Focus()
+	} else {
+		f.input.Focus()
+	}
+}
+
+func (f *formField) Blur() {
+	if f.multiline {
+		f.area.Blur()
+	} else {
+		f.input.Blur()
+	}
+}
+
+func (f formField) Update(msg tea.Msg) (formField, tea.Cmd) {
+	var cmd tea.Cmd
+	if f.multiline {
+		f.area, cmd = f.area.Update(msg)
+	} else {
+		f.input, cmd = f.input.Update(msg)
+	}
+	return f, cmd
+}
+
+func (f formField) View() string {
+	if f.multiline {
+		return f.area.View()
+	}
+	return f.input.View()
+}
+
+// newFormInput returns a text input styled for the form cards.
+func newFormInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "  "
+	ti.CharLimit = 256
+	ti.PromptStyle = lipgloss.NewStyle()
+	ti.TextStyle = lipgloss.NewStyle().Foreground(clrTextPri)
+	ti.PlaceholderStyle = lipgloss.NewStyle().Foreground(clrTextSec)
+	ti.Cursor.Style = lipgloss.NewStyle().Foreground(clrAccent)
+	return ti
+}
 
 func (m Model) initCreateForm() Model {
 	m.mode = modeForm
 	m.formEditing = false
 	m.formErr = ""
+	width := m.cardWidth()
 	for i := 0; i < numFields; i++ {
-		ti := textinput.New()
-		ti.Prompt = "  "
-		ti.CharLimit = 256
-		ti.PromptStyle = lipgloss.NewStyle()
-		ti.TextStyle = lipgloss.NewStyle().Foreground(clrTextPri)
-		ti.PlaceholderStyle = lipgloss.NewStyle().Foreground(clrTextSec)
-		ti.Cursor.Style = lipgloss.NewStyle().Foreground(clrAccent)
-		m.formFields[i] = ti
+		m.formFields[i] = newFormField(fieldHeights[i], width)
 	}
+	m.formFields[fieldTags].input.Placeholder = "comma separated, e.g. docker, cleanup"
+	m.formFields[fieldCat].input.ShowSuggestions = true
+	m.formFields[fieldCat].input.CompletionStyle = lipgloss.NewStyle().Foreground(clrTextMuted)
+	m.formCats = m.cfg.Categories()
+	m.formCatWarned = ""
 	m.formFocused = 0
 	m.formFields[0].Focus()
-	return m
+	return m.filterCategories()
 }
 
 func (m Model) initEditForm() Model {
@@ -59,7 +154,11 @@ func (m Model) initEditForm() Model {
 	m.formFields[fieldDesc].SetValue(cmd.Description)
 	m.formFields[fieldCmd].SetValue(cmd.Command)
 	m.formFields[fieldCat].SetValue(cmd.Category)
-	return m
+	m.formFields[fieldTags].SetValue(strings.Join(cmd.Tags, ", "))
+	if cmd.IsWorkflow() {
+		m.formFields[fieldCmd].area.Placeholder = "Runs " + strings.Join(cmd.Steps, ", ") + "; press " + keys.EditExternal.Help().Key + " in the list to change the steps"
+	}
+	return m.filterCategories()
 }
 
 func (m Model) handleFormKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -67,22 +166,34 @@ func (m Model) handleFormKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case key.Matches(msg, keys.ClearEsc):
 		m.mode = modeBrowse
 		return m, nil
-	case key.Matches(msg, keys.Select):
+	case key.Matches(msg, keys.FormSave):
+		return m.saveForm()
+	case key.Matches(msg, keys.Select) && !m.formFields[m.formFocused].multiline:
+		// In multi-line fields enter starts a new line instead.
 		return m.saveForm()
-	case key.Matches(msg, keys.FormTab):
+	case key.Matches(msg, keys.FormTab) && m.categorySuggestion() == "":
 		m.formFields[m.formFocused].Blur()
 		m.formFocused = (m.formFocused + 1) % numFields
 		m.formFields[m.formFocused].Focus()
-		return m, textinput.Blink
+		return m.syncCategoryHint(), textinput.Blink
 	case key.Matches(msg, keys.FormBackTab):
 		m.formFields[m.formFocused].Blur()
 		m.formFocused = (m.formFocused - 1 + numFields) % numFields
 		m.formFields[m.formFocused].Focus()
-		return m, textinput.Blink
+		return m.syncCategoryHint(), textinput.Blink
+	}
+	if m.formFocused == fieldCat {
+		if m, ok := m.handleCategoryChoiceKeys(msg); ok {
+			return m, nil
+		}
 	}
 
 	var cmd tea.Cmd
+	before := m.formFields[m.formFocused].Value()
 	m.formFields[m.formFocused], cmd = m.formFields[m.formFocused].Update(msg)
+	if m.formFocused == fieldCat && m.formFields[fieldCat].Value() != before {
+		m = m.filterCategories()
+	}
 	return m, cmd
 }
 
@@ -90,46 +201,72 @@ func (m Model) saveForm() (Model, tea.Cmd) {
 	name := strings.TrimSpace(m.formFields[fieldName].Value())
 	cmdText := strings.TrimSpace(m.formFields[fieldCmd].Value())
 
-	if name == "" {
-		m.formErr = "Name is required"
-		return m, nil
+	// Start from the existing command so fields the form doesn't show survive an edit.
+	var newCmd config.Command
+	skip := -1
+	if m.formEditing {
+		newCmd = m.commands[m.formEditIdx]
+		skip = m.formEditIdx
+	} else if m.tabs[m.activeTab] == projectTab {
+		newCmd.Origin = m.cfg.ProjectPath()
 	}
-	if cmdText == "" {
-		m.formErr = "Command is required"
+	newCmd.Name = name
+	newCmd.Description = strings.TrimSpace(m.formFields[fieldDesc].Value())
+	newCmd.Command = cmdText
+	newCmd.Category = strings.TrimSpace(m.formFields[fieldCat].Value())
+	newCmd.Tags = config.ParseTags(m.formFields[fieldTags].Value())
+
+	if err := config.Validate(m.commands, newCmd, skip); err != nil {
+		m.formErr = capitalize(err.Error())
 		return m, nil
 	}
-	for i, c := range m.commands {
-		if c.Name == name && !(m.formEditing && i == m.formEditIdx) {
-			m.formErr = fmt.Sprintf("Name %q already exists", name)
-			return m, nil
-		}
+	// A new category that looks like an existing one is usually a typo;
+	// saving again keeps it anyway.
+	if similar := m.similarCategory(); similar != "" && m.formCatWarned != newCmd.Category {
+		m.formCatWarned = newCmd.Category
+		m.formErr = fmt.Sprintf("Category %q is close to existing %q; save again to keep it", newCmd.Category, similar)
+		return m, nil
 	}
 
-	newCmd := config.Command{
-		Name:        name,
-		Description: strings.TrimSpace(m.formFields[fieldDesc].Value()),
-		Command:     cmdText,
-		Category:    strings.TrimSpace(m.formFields[fieldCat].Value()),
+	commands := slices.Clone(m.commands)
+	var renamed []int
+	if m.formEditing {
+		commands[m.formEditIdx] = newCmd
+		renamed = config.RenameStep(commands, m.commands[m.formEditIdx].Name, newCmd.Name)
+	} else {
+		commands = append(commands, newCmd)
 	}
 
+	if err := m.cfg.Save(commands); err != nil {
+		m.formErr = saveError("Save", err)
+		if errors.As(err, new(*config.ConflictError)) {
+			m.formErr += "; esc reloads"
+		}
+		return m, nil
+	}
 	if m.formEditing {
-		m.commands[m.formEditIdx] = newCmd
+		old := m.commands[m.formEditIdx]
+		m = m.recordStep(step{changes: withRenamedSteps(change{old: &old, new: &newCmd, index: m.formEditIdx}, m.commands, commands, renamed)})
 		m.statusMsg = "Command updated"
 	} else {
-		m.commands = append(m.commands, newCmd)
+		m = m.record(change{new: &newCmd, index: len(m.commands)})
 		m.statusMsg = "Command created"
 	}
-
-	if err := config.Save(m.commands); err != nil {
-		m.formErr = fmt.Sprintf("Save failed: %v", err)
-		return m, nil
-	}
+	m.commands = commands
 
 	m.mode = modeBrowse
 	m = m.refreshAfterMutation()
 	return m, nil
 }
 
+// capitalize upper-cases the first letter of an error message for display.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
 func (m Model) renderForm() string {
 	title := " New Command "
 	if m.formEditing {
@@ -137,6 +274,8 @@ func (m Model) renderForm() string {
 	}
 	header := formHeaderStyle.Render(title)
 
+	width := m.cardWidth()
+
 	var rows []string
 	for i := 0; i < numFields; i++ {
 		labelText := "  " + fieldLabels[i]
@@ -148,14 +287,21 @@ func (m Model) renderForm() string {
 			label = formLabelStyle.Render(labelText)
 		}
 
-		input := m.formFields[i].View()
-
+		row := label + "\n" + m.formFields[i].View()
 		if i == m.formFocused {
-			underline := formUnderlineStyle.Render("  " + strings.Repeat("─", 30))
-			rows = append(rows, label+"\n"+input+"\n"+underline)
-		} else {
-			rows = append(rows, label+"\n"+input)
+			row += "\n" + formUnderlineStyle.Render("  "+strings.Repeat("─", 30))
+		}
+		if i == fieldCat {
+			if warning := m.renderCategoryWarning(); warning != "" {
+				row += "\n" + warning
+			}
+			if i == m.formFocused {
+				if choices := m.renderCategoryChoices(width); choices != "" {
+					row += "\n" + choices
+				}
+			}
 		}
+		rows = append(rows, row)
 	}
 	body := strings.Join(rows, "\n\n")
 
@@ -168,9 +314,19 @@ func (m Model) renderForm() string {
 		header, "", body, errLine)
 	card := formContainerStyle.Render(content)
 
-	helpLine := helpKeyStyle.Render("tab") + helpDescStyle.Render(" next") +
+	enterHelp := " save"
+	if m.formFields[m.formFocused].multiline {
+		enterHelp = " new line"
+	}
+	tabHelp := " next"
+	if m.categorySuggestion() != "" {
+		tabHelp = " complete"
+	}
+	helpLine := helpKeyStyle.Render("tab") + helpDescStyle.Render(tabHelp) +
 		helpSepStyle.Render(" · ") +
-		helpKeyStyle.Render("enter") + helpDescStyle.Render(" save") +
+		helpKeyStyle.Render("enter") + helpDescStyle.Render(enterHelp) +
+		helpSepStyle.Render(" · ") +
+		helpKeyStyle.Render(keys.FormSave.Help().Key) + helpDescStyle.Render(" save") +
 		helpSepStyle.Render(" · ") +
 		helpKeyStyle.Render("esc") + helpDescStyle.Render(" cancel")
 
@@ -191,20 +347,21 @@ func (m Model) handleDeleteConfirmKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "y":
 		// Find and remove the command by name match
 		target := m.filtered[m.cursor]
-		for i, c := range m.commands {
-			if c.Name == target.Name {
-				m.commands = append(m.commands[:i], m.commands[i+1:]...)
-				break
-			}
-		}
+		i := config.Find(m.commands, target.Name)
+		commands := slices.Delete(slices.Clone(m.commands), i, i+1)
 
-		if err := config.Save(m.commands); err != nil {
-			m.statusMsg = fmt.Sprintf("Delete failed: %v", err)
+		if err := m.cfg.Save(commands); err != nil {
+			m.statusMsg = saveError("Delete", err)
 			m.mode = modeBrowse
 			return m, nil
 		}
+		m = m.record(change{old: &target, index: i})
+		m.commands = commands
 
-		m.statusMsg = "Command deleted"
+		m.statusMsg = "Deleted " + target.Name + " (" + keys.Undo.Help().Key + " to undo)"
+		if err := m.cfg.MoveToTrash(target); err != nil {
+			m.statusMsg = fmt.Sprintf("Deleted %s, but could not keep it in the trash: %v", target.Name, err)
+		}
 		m.mode = modeBrowse
 		m = m.refreshAfterMutation()
 	case "n":
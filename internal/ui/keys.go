@@ -9,28 +9,44 @@ import (
 )
 
 type keyMap struct {
-	Up       key.Binding
-	Down     key.Binding
-	NextTab  key.Binding
-	PrevTab  key.Binding
-	Search   key.Binding
-	ClearEsc key.Binding
-	Select   key.Binding
-	Copy     key.Binding
-	Quit     key.Binding
+	Up         key.Binding
+	Down       key.Binding
+	NextTab    key.Binding
+	PrevTab    key.Binding
+	NextSubTab key.Binding
+	PrevSubTab key.Binding
+	Search     key.Binding
+	ClearEsc   key.Binding
+	Select     key.Binding
+	Copy       key.Binding
+	Quit       key.Binding
 	// Command management
-	Create      key.Binding
-	Edit        key.Binding
-	Delete      key.Binding
-	FormTab     key.Binding
-	FormBackTab key.Binding
+	Create         key.Binding
+	Edit           key.Binding
+	EditExternal   key.Binding
+	Delete         key.Binding
+	Pin            key.Binding
+	RenameCategory key.Binding
+	Mark           key.Binding
+	MarkAll        key.Binding
+	Move           key.Binding
+	Tag            key.Binding
+	Export         key.Binding
+	Undo           key.Binding
+	Redo           key.Binding
+	FormTab        key.Binding
+	FormBackTab    key.Binding
+	FormSave       key.Binding
+	// Placeholder pick lists
+	ChoiceUp   key.Binding
+	ChoiceDown key.Binding
 }
 
 var keys keyMap
 
 // keyDisplayNames maps BubbleTea key identifiers to display characters.
 var keyDisplayNames = map[string]string{
-	"up": "↑", "down": "↓", "left": "←", "right": "→",
+	"up": "↑", "down": "↓", "left": "←", "right": "→", " ": "space",
 }
 
 func helpKeyLabel(keyList []string) string {
@@ -55,33 +71,50 @@ func buildBinding(configured []string, defaults []string, helpDesc string) key.B
 
 func initKeys(kb config.Keybindings) {
 	keys = keyMap{
-		Up:          buildBinding(kb.Up, []string{"up", "k"}, "up"),
-		Down:        buildBinding(kb.Down, []string{"down", "j"}, "down"),
-		NextTab:     buildBinding(kb.NextTab, []string{"tab"}, "next tab"),
-		PrevTab:     buildBinding(kb.PrevTab, []string{"shift+tab"}, "prev tab"),
-		Search:      buildBinding(kb.Search, []string{"/"}, "search"),
-		ClearEsc:    buildBinding(kb.ClearEsc, []string{"esc"}, "clear/exit search"),
-		Select:      buildBinding(kb.Select, []string{"enter"}, "select"),
-		Copy:        buildBinding(kb.Copy, []string{"c"}, "copy"),
-		Quit:        buildBinding(kb.Quit, []string{"q", "ctrl+c"}, "quit"),
-		Create:      buildBinding(kb.Create, []string{"n"}, "new"),
-		Edit:        buildBinding(kb.Edit, []string{"e"}, "edit"),
-		Delete:      buildBinding(kb.Delete, []string{"d"}, "delete"),
-		FormTab:     key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next field")),
-		FormBackTab: key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "prev field")),
+		Up:             buildBinding(kb.Up, []string{"up", "k"}, "up"),
+		Down:           buildBinding(kb.Down, []string{"down", "j"}, "down"),
+		NextTab:        buildBinding(kb.NextTab, []string{"tab"}, "next tab"),
+		PrevTab:        buildBinding(kb.PrevTab, []string{"shift+tab"}, "prev tab"),
+		NextSubTab:     buildBinding(kb.NextSubTab, []string{"right", "l"}, "next subcategory"),
+		PrevSubTab:     buildBinding(kb.PrevSubTab, []string{"left", "h"}, "prev subcategory"),
+		Search:         buildBinding(kb.Search, []string{"/"}, "search"),
+		ClearEsc:       buildBinding(kb.ClearEsc, []string{"esc"}, "clear/exit search"),
+		Select:         buildBinding(kb.Select, []string{"enter"}, "select"),
+		Copy:           buildBinding(kb.Copy, []string{"c"}, "copy"),
+		Quit:           buildBinding(kb.Quit, []string{"q", "ctrl+c"}, "quit"),
+		Create:         buildBinding(kb.Create, []string{"n"}, "new"),
+		Edit:           buildBinding(kb.Edit, []string{"e"}, "edit"),
+		EditExternal:   buildBinding(kb.EditExternal, []string{"E"}, "edit in $EDITOR"),
+		Delete:         buildBinding(kb.Delete, []string{"d"}, "delete"),
+		Pin:            buildBinding(kb.Pin, []string{"p"}, "pin"),
+		RenameCategory: buildBinding(kb.RenameCategory, []string{"r"}, "rename category"),
+		Mark:           buildBinding(kb.Mark, []string{" "}, "mark"),
+		MarkAll:        buildBinding(kb.MarkAll, []string{"a"}, "mark all"),
+		Move:           buildBinding(kb.Move, []string{"m"}, "move"),
+		Tag:            buildBinding(kb.Tag, []string{"t"}, "tag"),
+		Export:         buildBinding(kb.Export, []string{"x"}, "export"),
+		Undo:           buildBinding(kb.Undo, []string{"u"}, "undo"),
+		Redo:           buildBinding(kb.Redo, []string{"ctrl+r"}, "redo"),
+		FormTab:        key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next field")),
+		FormBackTab:    key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "prev field")),
+		FormSave:       key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "save")),
+		ChoiceUp:       key.NewBinding(key.WithKeys("up", "ctrl+p"), key.WithHelp("↑", "prev choice")),
+		ChoiceDown:     key.NewBinding(key.WithKeys("down", "ctrl+n"), key.WithHelp("↓", "next choice")),
 	}
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Up, k.Down, k.NextTab, k.Search, k.Select, k.Copy, k.Create, k.Edit, k.Delete, k.Quit}
+	return []key.Binding{k.Up, k.Down, k.NextTab, k.Search, k.Select, k.Copy, k.Pin, k.Create, k.Edit, k.Delete, k.Quit}
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down},
-		{k.NextTab, k.PrevTab},
+		{k.NextTab, k.PrevTab, k.NextSubTab, k.PrevSubTab},
 		{k.Search, k.ClearEsc},
-		{k.Select, k.Copy, k.Quit},
-		{k.Create, k.Edit, k.Delete},
+		{k.Select, k.Copy, k.Pin, k.Quit},
+		{k.Create, k.Edit, k.EditExternal, k.Delete},
+		{k.Mark, k.MarkAll, k.Move, k.Tag, k.Export},
+		{k.RenameCategory, k.Undo, k.Redo},
 	}
 }
@@ -2,31 +2,42 @@ package ui
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"tb/internal/clipboard"
 	"tb/internal/config"
+	"tb/internal/history"
+	"tb/internal/search"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/sahilm/fuzzy"
 )
 
 const (
 	modeBrowse = iota
 	modeForm
 	modeDeleteConfirm
+	modeFill
+	modeCategory
+	modeBulk
 )
 
 // Model is the main BubbleTea model for the command browser TUI.
 type Model struct {
-	commands     []config.Command // all commands from config
-	filtered     []config.Command // after category + search filter
-	tabs         []string         // "All" + category names
+	cfg          *config.Config
+	history      *history.Store
+	frecency     map[string]float64 // history score per command name
+	commands     []config.Command   // all commands from config
+	filtered     []config.Command   // after category + search filter
+	tabs         []string           // "All" + top-level category names
 	activeTab    int
+	subTabs      []string // active category + those nested in it; nil if none are
+	activeSubTab int
 	cursor       int
 	scrollOffset int
 	search       textinput.Model
@@ -37,18 +48,52 @@ type Model struct {
 
 	// Command management state
 	mode        int
-	formFields  [numFields]textinput.Model
+	formFields  [numFields]formField
 	formFocused int
 	formEditing bool
 	formEditIdx int
 	formErr     string
-	statusMsg   string
+	formCats    []string // existing categories offered by the category field
+	catMatches  []string // formCats filtered by the typed category
+	catCursor   int      // highlighted entry in catMatches, -1 for none
+	// formCatWarned is the category last saved despite looking like an
+	// existing one; saving it again confirms it.
+	formCatWarned string
+
+	// Category rename state
+	catRename string // the category being renamed
+	catMove   bool   // moving the marked commands instead of renaming
+	catInput  textinput.Model
+	catErr    string
+
+	// Multi-select state
+	marked    map[string]bool // names of the marked commands
+	bulkOp    int
+	bulkInput textinput.Model
+	bulkErr   string
+
+	statusMsg string
+	undoStack []step
+	redoStack []step
+
+	// Placeholder fill-in state
+	fillID      int // bumped per form so stale choice results are dropped
+	fillCmd     config.Command
+	fillFields  []fillField
+	fillFocused int
+	fillErr     string
 }
 
-// New creates the TUI model from loaded commands.
+// Virtual tabs shown ahead of the category tabs.
+const (
+	pinnedTab  = "★ Pinned"
+	projectTab = "Project"
+)
+
+// New creates the TUI model from the loaded config.
 // Must be called after the lipgloss default renderer is configured.
-func New(commands []config.Command, categories []string, kb config.Keybindings) Model {
-	initKeys(kb)
+func New(cfg *config.Config, hist *history.Store) Model {
+	initKeys(cfg.Keybindings)
 	initStyles()
 	ti := textinput.New()
 	ti.Placeholder = "type to search..."
@@ -59,19 +104,28 @@ func New(commands []config.Command, categories []string, kb config.Keybindings)
 	ti.Cursor.Style = lipgloss.NewStyle().Foreground(clrAccent)
 	ti.CharLimit = 100
 
-	tabs := append([]string{"All"}, categories...)
-
 	m := Model{
-		commands: commands,
-		tabs:     tabs,
+		cfg:      cfg,
+		history:  hist,
+		frecency: hist.Scores(time.Now()),
+		commands: slices.Clone(cfg.Commands),
 		search:   ti,
 	}
+	m = m.buildTabs().setTab(0)
 	m = m.filterCommands()
+	m.statusMsg = stepWarning(m.commands)
 	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, watchConfig())
+}
+
+// WithStatus shows msg on the status line, e.g. a config problem found at
+// startup.
+func (m Model) WithStatus(msg string) Model {
+	m.statusMsg = msg
+	return m
 }
 
 // Selected returns the command chosen by the user, or nil if they quit.
@@ -86,14 +140,20 @@ func (m Model) innerWidth() int {
 	return m.width - 4
 }
 
+func (m Model) cardWidth() int {
+	// Keep the form and fill cards inside the frame: card border(2) +
+	// padding(4) + indent(4)
+	return max(20, min(60, m.innerWidth()-10))
+}
+
 func (m Model) innerHeight() int {
 	// frame border(2)
 	return m.height - 2
 }
 
 func (m Model) bodyHeight() int {
-	// inner height minus tabs(1) + rule(1) + search/status(1) + help(1)
-	return m.innerHeight() - 4
+	// inner height minus tabs(1 or 2) + rule(1) + search/status(1) + help(1)
+	return m.innerHeight() - 3 - m.tabRows()
 }
 
 func (m Model) listHeight() int {
@@ -110,6 +170,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case choicesMsg:
+		return m.handleChoices(msg), nil
+
+	case configCheckMsg:
+		return m.checkConfig(), watchConfig()
+
+	case editorDoneMsg:
+		return m.handleEditorDone(msg)
+
 	case tea.KeyMsg:
 		if key.Matches(msg, keys.Quit) && msg.Type == tea.KeyCtrlC {
 			return m, tea.Quit
@@ -120,6 +189,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleFormKeys(msg)
 		case modeDeleteConfirm:
 			return m.handleDeleteConfirmKeys(msg)
+		case modeFill:
+			return m.handleFillKeys(msg)
+		case modeCategory:
+			return m.handleCategoryKeys(msg)
+		case modeBulk:
+			return m.handleBulkKeys(msg)
 		default:
 			// Clear status message on any keypress in browse mode
 			m.statusMsg = ""
@@ -141,9 +216,7 @@ func (m Model) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 	if key.Matches(msg, keys.Select) {
 		if len(m.filtered) > 0 {
-			cmd := m.filtered[m.cursor]
-			m.selected = &cmd
-			return m, tea.Quit
+			return m.choose(m.filtered[m.cursor])
 		}
 		return m, nil
 	}
@@ -166,9 +239,7 @@ func (m Model) handleNavigationKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, textinput.Blink
 	case key.Matches(msg, keys.Select):
 		if len(m.filtered) > 0 {
-			cmd := m.filtered[m.cursor]
-			m.selected = &cmd
-			return m, tea.Quit
+			return m.choose(m.filtered[m.cursor])
 		}
 	case key.Matches(msg, keys.Up):
 		if m.cursor > 0 {
@@ -179,12 +250,22 @@ func (m Model) handleNavigationKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.cursor++
 		}
 	case key.Matches(msg, keys.NextTab):
-		m.activeTab = (m.activeTab + 1) % len(m.tabs)
+		m = m.setTab((m.activeTab + 1) % len(m.tabs))
 		m = m.filterCommands()
 		m.cursor = 0
 		m.scrollOffset = 0
 	case key.Matches(msg, keys.PrevTab):
-		m.activeTab = (m.activeTab - 1 + len(m.tabs)) % len(m.tabs)
+		m = m.setTab((m.activeTab - 1 + len(m.tabs)) % len(m.tabs))
+		m = m.filterCommands()
+		m.cursor = 0
+		m.scrollOffset = 0
+	case key.Matches(msg, keys.NextSubTab) && len(m.subTabs) > 0:
+		m.activeSubTab = (m.activeSubTab + 1) % len(m.subTabs)
+		m = m.filterCommands()
+		m.cursor = 0
+		m.scrollOffset = 0
+	case key.Matches(msg, keys.PrevSubTab) && len(m.subTabs) > 0:
+		m.activeSubTab = (m.activeSubTab - 1 + len(m.subTabs)) % len(m.subTabs)
 		m = m.filterCommands()
 		m.cursor = 0
 		m.scrollOffset = 0
@@ -196,19 +277,54 @@ func (m Model) handleNavigationKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m = m.initEditForm()
 			return m, textinput.Blink
 		}
+	case key.Matches(msg, keys.EditExternal):
+		if len(m.filtered) > 0 {
+			return m.editInEditor()
+		}
 	case key.Matches(msg, keys.Copy):
 		if len(m.filtered) > 0 {
-			cmd := m.filtered[m.cursor]
+			cmd, err := config.Resolve(m.commands, m.filtered[m.cursor])
+			if err != nil {
+				m.statusMsg = capitalize(err.Error())
+				break
+			}
 			if err := clipboard.Write(cmd.Command); err != nil {
 				m.statusMsg = "Clipboard unavailable"
 			} else {
+				_ = m.history.Record(cmd.Name)
 				m.statusMsg = "Copied to clipboard"
 			}
 		}
+	case key.Matches(msg, keys.Delete) && len(m.marked) > 0:
+		return m.initBulk(bulkDelete)
 	case key.Matches(msg, keys.Delete):
 		if len(m.filtered) > 0 {
 			m.mode = modeDeleteConfirm
 		}
+	case key.Matches(msg, keys.Mark):
+		if len(m.filtered) > 0 {
+			m = m.toggleMark()
+		}
+	case key.Matches(msg, keys.MarkAll):
+		m = m.toggleMarkAll()
+	case key.Matches(msg, keys.ClearEsc):
+		m.marked = nil
+	case key.Matches(msg, keys.Move):
+		return m.initMoveCategory()
+	case key.Matches(msg, keys.Tag):
+		return m.initBulk(bulkTag)
+	case key.Matches(msg, keys.Export):
+		return m.initBulk(bulkExport)
+	case key.Matches(msg, keys.Pin):
+		if len(m.filtered) > 0 {
+			return m.togglePin(), nil
+		}
+	case key.Matches(msg, keys.RenameCategory):
+		return m.initRenameCategory()
+	case key.Matches(msg, keys.Undo):
+		return m.undo(), nil
+	case key.Matches(msg, keys.Redo):
+		return m.redo(), nil
 	}
 
 	m = m.adjustScroll()
@@ -232,45 +348,41 @@ func (m Model) adjustScroll() Model {
 func (m Model) filterCommands() Model {
 	// Step 1: filter by category
 	var pool []config.Command
-	if m.activeTab == 0 { // "All"
-		pool = m.commands
-	} else {
-		cat := m.tabs[m.activeTab]
+	switch tab := m.tabs[m.activeTab]; {
+	case m.activeTab == 0: // "All": pinned first, then most frecent
+		pool = slices.Clone(m.commands)
+		sort.SliceStable(pool, func(i, j int) bool {
+			if pool[i].Pinned != pool[j].Pinned {
+				return pool[i].Pinned
+			}
+			return m.frecency[pool[i].Name] > m.frecency[pool[j].Name]
+		})
+	case tab == pinnedTab:
 		for _, cmd := range m.commands {
-			if cmd.Category == cat {
+			if cmd.Pinned {
+				pool = append(pool, cmd)
+			}
+		}
+	case tab == projectTab && m.cfg.ProjectPath() != "":
+		for _, cmd := range m.commands {
+			if cmd.Origin == m.cfg.ProjectPath() {
+				pool = append(pool, cmd)
+			}
+		}
+	default: // a category, with everything nested below it
+		cat := m.currentCategory()
+		for _, cmd := range m.commands {
+			if config.InCategory(cmd.Category, cat) {
 				pool = append(pool, cmd)
 			}
 		}
 	}
 
-	// Step 2: fuzzy search
-	query := m.search.Value()
-	if query == "" {
-		m.filtered = pool
-		return m
-	}
-
-	source := commandSource(pool)
-	matches := fuzzy.FindFrom(query, source)
-	result := make([]config.Command, len(matches))
-	for i, match := range matches {
-		result[i] = pool[match.Index]
-	}
-	m.filtered = result
+	// Step 2: tag filter and fuzzy search
+	m.filtered = search.Filter(pool, m.search.Value(), m.frecency)
 	return m
 }
 
-// commandSource adapts []config.Command for sahilm/fuzzy.
-type commandSource []config.Command
-
-func (s commandSource) String(i int) string {
-	return s[i].Name + " " + s[i].Description
-}
-
-func (s commandSource) Len() int {
-	return len(s)
-}
-
 // ── View ────────────────────────────────────────────────────────────
 
 func (m Model) View() string {
@@ -288,6 +400,8 @@ func (m Model) View() string {
 	switch m.mode {
 	case modeForm:
 		inner = m.renderForm()
+	case modeFill:
+		inner = m.renderFill()
 	case modeDeleteConfirm:
 		tabBar := m.renderTabs()
 		rule := m.thinRule()
@@ -296,6 +410,20 @@ func (m Model) View() string {
 		helpView := m.renderHelp()
 		inner = lipgloss.JoinVertical(lipgloss.Left,
 			tabBar, rule, confirm, helpView)
+	case modeCategory:
+		tabBar := m.renderTabs()
+		rule := m.thinRule()
+		dialog := m.renderRenameCategory(iw, m.bodyHeight()+1) // body + search line
+		helpView := m.renderHelp()
+		inner = lipgloss.JoinVertical(lipgloss.Left,
+			tabBar, rule, dialog, helpView)
+	case modeBulk:
+		tabBar := m.renderTabs()
+		rule := m.thinRule()
+		dialog := m.renderBulk(iw, m.bodyHeight()+1) // body + search line
+		helpView := m.renderHelp()
+		inner = lipgloss.JoinVertical(lipgloss.Left,
+			tabBar, rule, dialog, helpView)
 	default:
 		tabBar := m.renderTabs()
 		rule := m.thinRule()
@@ -303,6 +431,8 @@ func (m Model) View() string {
 		var searchOrStatus string
 		if m.statusMsg != "" {
 			searchOrStatus = statusMsgStyle.Render(" " + m.statusMsg)
+		} else if len(m.marked) > 0 && !m.searchActive {
+			searchOrStatus = m.renderMarked()
 		} else {
 			searchOrStatus = m.renderSearchBar()
 		}
@@ -321,23 +451,6 @@ func (m Model) thinRule() string {
 	return thinRuleStyle.Render(strings.Repeat("─", m.innerWidth()))
 }
 
-func (m Model) renderTabs() string {
-	var tabs []string
-	for i, name := range m.tabs {
-		if i == m.activeTab {
-			tabs = append(tabs, activeTabStyle.Render(name))
-		} else {
-			tabs = append(tabs, inactiveTabStyle.Render(name))
-		}
-	}
-	row := lipgloss.JoinHorizontal(lipgloss.Bottom, tabs...)
-	// Clamp to inner width so overflowing tabs don't break layout geometry.
-	if lipgloss.Width(row) > m.innerWidth() {
-		row = lipgloss.NewStyle().MaxWidth(m.innerWidth()).Render(row)
-	}
-	return row
-}
-
 func (m Model) renderBody() string {
 	iw := m.innerWidth()
 	bh := m.bodyHeight()
@@ -372,14 +485,22 @@ func (m Model) renderList(width int) string {
 	end := min(m.scrollOffset+lh, len(m.filtered))
 	for i := m.scrollOffset; i < end; i++ {
 		name := m.filtered[i].Name
+		// The marker column only shows while something is marked.
+		mark := ""
+		if len(m.marked) > 0 {
+			mark = "  "
+			if m.marked[name] {
+				mark = markStyle.Render("● ")
+			}
+		}
 		if i == m.cursor {
-			cursor := cursorStyle.Render(" > ")
+			cursor := cursorStyle.Render(" > ") + mark
 			name = selectedItemStyle.
 				Width(width - lipgloss.Width(cursor)).
 				Render(name)
 			lines = append(lines, cursor+name)
 		} else {
-			lines = append(lines, "   "+normalItemStyle.Render(name))
+			lines = append(lines, "   "+mark+normalItemStyle.Render(name))
 		}
 	}
 
@@ -409,13 +530,29 @@ func (m Model) renderDetail(width int) string {
 	desc := detailLabelStyle.Render("Description") + "\n" +
 		detailValueStyle.Width(width).Render(cmd.Description)
 
-	cmdText := detailLabelStyle.Render("Command") + "\n" +
-		commandValueStyle.Width(width).Render(cmd.Command)
-
-	parts := []string{title, "", desc, "", cmdText}
+	parts := []string{title, "", desc, ""}
+	if cmd.IsWorkflow() {
+		steps := make([]string, len(cmd.Steps))
+		for i, name := range cmd.Steps {
+			steps[i] = fmt.Sprintf("%d. %s", i+1, name)
+		}
+		parts = append(parts, detailLabelStyle.Render("Steps")+"\n"+
+			detailValueStyle.Width(width).Render(strings.Join(steps, "\n")), "")
+		cmd, _ = config.Resolve(m.commands, cmd)
+	}
+	parts = append(parts, detailLabelStyle.Render("Command")+"\n"+
+		commandValueStyle.Width(width).Render(cmd.Command))
 
 	if cmd.Category != "" {
-		parts = append(parts, "", categoryTagStyle.Render(cmd.Category))
+		parts = append(parts, "", categoryTagStyle.Render(m.cfg.CategoryLabel(cmd.Category)))
+	}
+
+	if len(cmd.Tags) > 0 {
+		chips := make([]string, len(cmd.Tags))
+		for i, tag := range cmd.Tags {
+			chips[i] = tagChipStyle.Render("#" + tag)
+		}
+		parts = append(parts, "", lipgloss.NewStyle().Width(width).Render(strings.Join(chips, " ")))
 	}
 
 	return strings.Join(parts, "\n")
@@ -439,36 +576,61 @@ func (m Model) renderHelp() string {
 	return lipgloss.NewStyle().MaxWidth(m.innerWidth()).Render(line)
 }
 
-// refreshAfterMutation rebuilds tabs, filters, and clamps cursor after a command list change.
-func (m Model) refreshAfterMutation() Model {
-	// Preserve current tab name so we can re-find it after rebuild
-	currentTab := ""
-	if m.activeTab < len(m.tabs) {
-		currentTab = m.tabs[m.activeTab]
+// togglePin flips the pinned flag of the highlighted command and saves it.
+func (m Model) togglePin() Model {
+	name := m.filtered[m.cursor].Name
+	commands := slices.Clone(m.commands)
+	i := config.Find(commands, name)
+	commands[i].Pinned = !commands[i].Pinned
+
+	if err := m.cfg.Save(commands); err != nil {
+		m.statusMsg = saveError("Pin", err)
+		return m
+	}
+	old, updated := m.commands[i], commands[i]
+	m = m.record(change{old: &old, new: &updated, index: i})
+	m.commands = commands
+	if commands[i].Pinned {
+		m.statusMsg = "Pinned " + name
+	} else {
+		m.statusMsg = "Unpinned " + name
 	}
 
-	seen := make(map[string]struct{})
-	for _, cmd := range m.commands {
-		if cmd.Category != "" {
-			seen[cmd.Category] = struct{}{}
-		}
+	m = m.refreshAfterMutation()
+	// Keep the cursor on the command even though pinning reorders the list.
+	if j := config.Find(m.filtered, name); j >= 0 {
+		m.cursor = j
+		m = m.adjustScroll()
 	}
-	cats := make([]string, 0, len(seen))
-	for cat := range seen {
-		cats = append(cats, cat)
+	return m
+}
+
+// fixedTabs returns the tabs shown ahead of the category tabs.
+func (m Model) fixedTabs() []string {
+	tabs := []string{"All"}
+	if slices.ContainsFunc(m.commands, func(c config.Command) bool { return c.Pinned }) {
+		tabs = append(tabs, pinnedTab)
 	}
-	sort.Strings(cats)
-	m.tabs = append([]string{"All"}, cats...)
-
-	// Re-find the tab by name; fall back to "All" if the category was removed
-	m.activeTab = 0
-	for i, t := range m.tabs {
-		if t == currentTab {
-			m.activeTab = i
-			break
-		}
+	if m.cfg.ProjectPath() != "" {
+		tabs = append(tabs, projectTab)
 	}
+	return tabs
+}
+
+// refreshAfterMutation rebuilds tabs, filters, and clamps cursor after a command list change.
+func (m Model) refreshAfterMutation() Model {
+	return m.refreshTabs(m.currentTab())
+}
 
+// refreshTabs rebuilds the tabs and then shows tab, which is a fixed tab's
+// name or a category, falling back to All if it is gone.
+func (m Model) refreshTabs(tab string) Model {
+	for name := range m.marked {
+		if config.Find(m.commands, name) < 0 {
+			delete(m.marked, name)
+		}
+	}
+	m = m.buildTabs().selectTab(tab)
 	m = m.filterCommands()
 
 	if m.cursor >= len(m.filtered) {
@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tb/internal/config"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PickItem is one row of the multi-select picker.
+type PickItem struct {
+	Title  string // e.g. a suggested command name
+	Detail string // e.g. the command line
+	Note   string // short right-hand hint, e.g. "12×"
+}
+
+// Picker is a standalone multi-select list, used by tb import to choose
+// which entries become commands.
+type Picker struct {
+	title        string
+	items        []PickItem
+	marked       []bool
+	cursor       int
+	scrollOffset int
+	width        int
+	height       int
+	confirmed    bool
+}
+
+// NewPicker creates a picker over items with nothing marked.
+// Must be called after the lipgloss default renderer is configured.
+func NewPicker(title string, items []PickItem, kb config.Keybindings) Picker {
+	initKeys(kb)
+	initStyles()
+	return Picker{
+		title:  title,
+		items:  items,
+		marked: make([]bool, len(items)),
+	}
+}
+
+func (p Picker) Init() tea.Cmd {
+	return nil
+}
+
+// Chosen returns the indices of the marked items in order, or nil if the
+// user cancelled.
+func (p Picker) Chosen() []int {
+	if !p.confirmed {
+		return nil
+	}
+	var chosen []int
+	for i, marked := range p.marked {
+		if marked {
+			chosen = append(chosen, i)
+		}
+	}
+	return chosen
+}
+
+func (p Picker) listHeight() int {
+	// frame border(2) + header(1) + rule(1) + status(1) + help(1)
+	return p.height - 6
+}
+
+func (p Picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width = msg.Width
+		p.height = msg.Height
+		return p, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keys.Quit), key.Matches(msg, keys.ClearEsc):
+			return p, tea.Quit
+		case key.Matches(msg, keys.Select):
+			p.confirmed = true
+			return p, tea.Quit
+		case key.Matches(msg, keys.Up):
+			if p.cursor > 0 {
+				p.cursor--
+			}
+		case key.Matches(msg, keys.Down):
+			if p.cursor < len(p.items)-1 {
+				p.cursor++
+			}
+		case key.Matches(msg, keys.Mark):
+			if len(p.items) > 0 {
+				p.marked[p.cursor] = !p.marked[p.cursor]
+				if p.cursor < len(p.items)-1 {
+					p.cursor++
+				}
+			}
+		case key.Matches(msg, keys.MarkAll):
+			// Mark everything, or clear if everything is already marked.
+			all := true
+			for _, marked := range p.marked {
+				all = all && marked
+			}
+			for i := range p.marked {
+				p.marked[i] = !all
+			}
+		}
+
+		// Keep the cursor inside the visible window.
+		if lh := p.listHeight(); lh > 0 {
+			if p.cursor < p.scrollOffset {
+				p.scrollOffset = p.cursor
+			}
+			if p.cursor >= p.scrollOffset+lh {
+				p.scrollOffset = p.cursor - lh + 1
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p Picker) View() string {
+	if p.width == 0 {
+		return "Loading..."
+	}
+	if p.width < 40 || p.height < 10 {
+		return "Terminal too small. Please resize to at least 40x10."
+	}
+
+	iw := p.width - 4 // frame border(2) + frame horizontal padding(2)
+	header := formHeaderStyle.Render(" " + p.title + " ")
+	rule := thinRuleStyle.Render(strings.Repeat("─", iw))
+
+	lh := p.listHeight()
+	var lines []string
+	end := min(p.scrollOffset+lh, len(p.items))
+	for i := p.scrollOffset; i < end; i++ {
+		item := p.items[i]
+		box := "[ ] "
+		if p.marked[i] {
+			box = "[x] "
+		}
+		cursor := "   "
+		if i == p.cursor {
+			cursor = cursorStyle.Render(" > ")
+		}
+		note := scrollIndicatorStyle.Render(fmt.Sprintf("%5s ", item.Note))
+		title := normalItemStyle.Render(fmt.Sprintf("%-24s ", item.Title))
+		if i == p.cursor {
+			title = selectedItemStyle.Render(fmt.Sprintf("%-24s", item.Title)) + " "
+		}
+		detail := commandValueStyle.Render(strings.ReplaceAll(item.Detail, "\n", " ⏎ "))
+		line := cursor + box + note + title + detail
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(iw).Render(line))
+	}
+	if len(p.items) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(clrTextSec).Render("  Nothing to import"))
+	}
+	for len(lines) < lh {
+		lines = append(lines, "")
+	}
+
+	count := 0
+	for _, marked := range p.marked {
+		if marked {
+			count++
+		}
+	}
+	status := statusMsgStyle.Render(fmt.Sprintf(" %d of %d selected", count, len(p.items)))
+
+	helpLine := " " + helpKeyStyle.Render(keys.Mark.Help().Key) + helpDescStyle.Render(" mark") +
+		helpSepStyle.Render(" · ") +
+		helpKeyStyle.Render(keys.MarkAll.Help().Key) + helpDescStyle.Render(" all") +
+		helpSepStyle.Render(" · ") +
+		helpKeyStyle.Render(keys.Select.Help().Key) + helpDescStyle.Render(" import") +
+		helpSepStyle.Render(" · ") +
+		helpKeyStyle.Render(keys.ClearEsc.Help().Key) + helpDescStyle.Render(" cancel")
+
+	inner := lipgloss.JoinVertical(lipgloss.Left,
+		header, rule, strings.Join(lines, "\n"), status, helpLine)
+	// lipgloss counts padding inside Width, so add it back for the content to get iw columns.
+	return frameStyle.
+		Width(iw + 2).
+		Height(p.height - 2).
+		Render(inner)
+}
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"tb/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configPollInterval is how often the config files are checked for changes
+// made outside this session, e.g. in an editor.
+const configPollInterval = time.Second
+
+// configCheckMsg asks the model to check whether the config changed on disk.
+type configCheckMsg struct{}
+
+func watchConfig() tea.Cmd {
+	return tea.Tick(configPollInterval, func(time.Time) tea.Msg {
+		return configCheckMsg{}
+	})
+}
+
+// checkConfig reloads the commands if a config file changed on disk. Forms
+// hold indexes into m.commands, so a reload waits until the user is back
+// to browsing.
+func (m Model) checkConfig() Model {
+	if m.mode != modeBrowse || !m.cfg.Changed() {
+		return m
+	}
+	if err := m.cfg.Reload(); err != nil {
+		m.statusMsg = "Config changed but has errors, run tb lint"
+		return m
+	}
+
+	var name string
+	if m.cursor < len(m.filtered) {
+		name = m.filtered[m.cursor].Name
+	}
+	m.commands = slices.Clone(m.cfg.Commands)
+	m = m.refreshAfterMutation()
+	if j := config.Find(m.filtered, name); j >= 0 {
+		m.cursor = j
+		m = m.adjustScroll()
+	}
+	if m.statusMsg == "" { // don't hide why a save was refused
+		m.statusMsg = "Reloaded config"
+		if warning := stepWarning(m.commands); warning != "" {
+			m.statusMsg = "Reloaded config. " + warning
+		}
+	}
+	return m
+}
+
+// stepWarning describes the workflows whose steps are broken, or returns ""
+// if there are none. They still load, but fail when selected.
+func stepWarning(commands []config.Command) string {
+	broken := config.BrokenWorkflows(commands)
+	for _, cmd := range commands {
+		if err := broken[cmd.Name]; err != nil {
+			msg := capitalize(err.Error())
+			if len(broken) > 1 {
+				msg += fmt.Sprintf(" (and %d more)", len(broken)-1)
+			}
+			return msg + ", run tb lint"
+		}
+	}
+	return ""
+}
+
+// saveError describes a failed save for the status line or form. Conflicts
+// get no prefix: the message names the commands and the reload that follows
+// shows their new state.
+func saveError(action string, err error) string {
+	var conflict *config.ConflictError
+	if errors.As(err, &conflict) {
+		return capitalize(err.Error()) + ", not saved"
+	}
+	return action + " failed: " + err.Error()
+}
@@ -30,14 +30,16 @@ var (
 	thinRuleStyle lipgloss.Style
 
 	// ── Tab styles ──────────────────────────────────────────────────
-	activeTabStyle   lipgloss.Style
-	inactiveTabStyle lipgloss.Style
+	activeTabStyle    lipgloss.Style
+	activeSubTabStyle lipgloss.Style
+	inactiveTabStyle  lipgloss.Style
 
 	// ── List item styles ────────────────────────────────────────────
 	cursorStyle          lipgloss.Style
 	selectedItemStyle    lipgloss.Style
 	normalItemStyle      lipgloss.Style
 	scrollIndicatorStyle lipgloss.Style
+	markStyle            lipgloss.Style
 
 	// ── Detail pane styles ──────────────────────────────────────────
 	detailBoxStyle    lipgloss.Style
@@ -46,6 +48,7 @@ var (
 	detailValueStyle  lipgloss.Style
 	commandValueStyle lipgloss.Style
 	categoryTagStyle  lipgloss.Style
+	tagChipStyle      lipgloss.Style
 
 	// ── Search bar styles ───────────────────────────────────────────
 	searchPromptStyle lipgloss.Style
@@ -86,6 +89,11 @@ func initStyles() {
 		Background(clrAccent).
 		Padding(0, 2)
 
+	activeSubTabStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(clrAccent).
+		Padding(0, 1)
+
 	inactiveTabStyle = lipgloss.NewStyle().
 		Foreground(clrTextSec).
 		Padding(0, 1)
@@ -102,6 +110,9 @@ func initStyles() {
 	normalItemStyle = lipgloss.NewStyle().
 		Foreground(clrTextPri)
 
+	markStyle = lipgloss.NewStyle().
+		Foreground(clrAccent)
+
 	scrollIndicatorStyle = lipgloss.NewStyle().
 		Foreground(clrTextSec)
 
@@ -128,6 +139,11 @@ func initStyles() {
 		Foreground(clrTextSec).
 		Italic(true)
 
+	tagChipStyle = lipgloss.NewStyle().
+		Foreground(clrAccentDim).
+		Background(clrHighBg).
+		Padding(0, 1)
+
 	searchPromptStyle = lipgloss.NewStyle().
 		Foreground(clrAccent)
 
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+
+	"tb/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// categoriesOf returns the categories in use, with the parents of nested
+// ones filled in.
+func categoriesOf(commands []config.Command) []string {
+	var cats []string
+	for _, cmd := range commands {
+		if cmd.Category != "" && !slices.Contains(cats, cmd.Category) {
+			cats = append(cats, cmd.Category)
+		}
+	}
+	return config.WithParents(cats)
+}
+
+// buildTabs sets the top-level tabs: the fixed tabs, then one per
+// top-level category.
+func (m Model) buildTabs() Model {
+	var roots []string
+	for _, cat := range categoriesOf(m.commands) {
+		if !strings.Contains(cat, config.CategorySep) {
+			roots = append(roots, cat)
+		}
+	}
+	m.tabs = append(m.fixedTabs(), m.cfg.OrderCategories(roots)...)
+	return m
+}
+
+// subTabsFor returns the second tab row for a top-level category: root
+// itself, standing for everything below it, then each nested category.
+// It is nil when nothing is nested under root.
+func (m Model) subTabsFor(root string) []string {
+	var nested []string
+	for _, cat := range categoriesOf(m.commands) {
+		if cat != root && config.InCategory(cat, root) {
+			nested = append(nested, cat)
+		}
+	}
+	if len(nested) == 0 {
+		return nil
+	}
+	return append([]string{root}, m.cfg.OrderCategories(nested)...)
+}
+
+// currentCategory returns the category whose commands are listed, or ""
+// on the fixed tabs.
+func (m Model) currentCategory() string {
+	switch {
+	case m.activeTab < len(m.fixedTabs()) || m.activeTab >= len(m.tabs):
+		return ""
+	case len(m.subTabs) > 0:
+		return m.subTabs[m.activeSubTab]
+	}
+	return m.tabs[m.activeTab]
+}
+
+// currentTab names the active tab: a fixed tab's name or a category.
+func (m Model) currentTab() string {
+	if cat := m.currentCategory(); cat != "" {
+		return cat
+	}
+	if m.activeTab < len(m.tabs) {
+		return m.tabs[m.activeTab]
+	}
+	return ""
+}
+
+// setTab activates tab i of the top row, starting at its first sub-tab.
+func (m Model) setTab(i int) Model {
+	m.activeTab = i
+	m.subTabs = nil
+	m.activeSubTab = 0
+	if i >= len(m.fixedTabs()) {
+		m.subTabs = m.subTabsFor(m.tabs[i])
+	}
+	return m
+}
+
+// selectTab activates the tab named by currentTab. A category that no
+// longer has a tab falls back to its nearest parent, then to All.
+func (m Model) selectTab(name string) Model {
+	fixed := len(m.fixedTabs())
+	if i := slices.Index(m.tabs[:fixed], name); i >= 0 {
+		return m.setTab(i)
+	}
+	i := slices.Index(m.tabs[fixed:], config.RootCategory(name))
+	if i < 0 {
+		return m.setTab(0)
+	}
+	m = m.setTab(fixed + i)
+	for cat := name; cat != ""; {
+		if j := slices.Index(m.subTabs, cat); j >= 0 {
+			m.activeSubTab = j
+			break
+		}
+		k := strings.LastIndex(cat, config.CategorySep)
+		if k < 0 {
+			break
+		}
+		cat = cat[:k]
+	}
+	return m
+}
+
+// tabRows returns how many lines the tab rows take.
+func (m Model) tabRows() int {
+	if len(m.subTabs) > 0 {
+		return 2
+	}
+	return 1
+}
+
+func (m Model) renderTabs() string {
+	fixed := len(m.fixedTabs())
+	labels := make([]string, len(m.tabs))
+	for i, name := range m.tabs {
+		labels[i] = name
+		if i >= fixed {
+			labels[i] = m.cfg.CategoryLabel(name)
+		}
+	}
+	row := tabRow(labels, m.activeTab, activeTabStyle, inactiveTabStyle, m.innerWidth())
+	if len(m.subTabs) == 0 {
+		return row
+	}
+
+	root := m.subTabs[0]
+	labels = []string{"all"}
+	for _, cat := range m.subTabs[1:] {
+		labels = append(labels, m.cfg.SubCategoryLabel(cat, root))
+	}
+	sub := tabRow(labels, m.activeSubTab, activeSubTabStyle, inactiveTabStyle, m.innerWidth()-2)
+	return row + "\n  " + sub
+}
+
+// tabRow renders one row of tabs within width. When they don't fit, tabs
+// scroll off the left so the active one stays in view.
+func tabRow(labels []string, active int, activeStyle, inactiveStyle lipgloss.Style, width int) string {
+	tabs := make([]string, len(labels))
+	for i, label := range labels {
+		if i == active {
+			tabs[i] = activeStyle.Render(label)
+		} else {
+			tabs[i] = inactiveStyle.Render(label)
+		}
+	}
+	more := scrollIndicatorStyle.Render("‹ ")
+	start := 0
+	for start < active && lipgloss.Width(more)+lipgloss.Width(lipgloss.JoinHorizontal(lipgloss.Bottom, tabs[start:active+1]...)) > width {
+		start++
+	}
+	row := lipgloss.JoinHorizontal(lipgloss.Bottom, tabs[start:]...)
+	if start > 0 {
+		row = more + row
+	}
+	// Clamp to width so overflowing tabs don't break layout geometry.
+	if lipgloss.Width(row) > width {
+		row = lipgloss.NewStyle().MaxWidth(width).Render(row)
+	}
+	return row
+}
@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"tb/internal/config"
+)
+
+// maxUndo caps the undo history of a session.
+const maxUndo = 100
+
+// change is one undoable mutation of the command list. old is nil for a
+// create and new is nil for a delete.
+type change struct {
+	old, new *config.Command
+	index    int // position of the command in m.commands
+}
+
+// step is what one undo or redo reverts or reapplies: a single change, or
+// several saved together, such as renaming a category.
+type step struct {
+	changes []change
+	label   string // describes a multi-command step, e.g. "rename of docker"
+	// renamed holds the old and new category name when the step also
+	// renamed that category's categories: entry.
+	renamed [2]string
+}
+
+// record pushes a change made by the user, which invalidates the redo stack.
+func (m Model) record(c change) Model {
+	return m.recordStep(step{changes: []change{c}})
+}
+
+// withRenamedSteps returns c followed by the changes to the workflows at
+// indexes, whose steps were renamed along with c's command, so undoing the
+// rename restores them too.
+func withRenamedSteps(c change, before, after []config.Command, indexes []int) []change {
+	changes := []change{c}
+	for _, i := range indexes {
+		old, updated := before[i], after[i]
+		changes = append(changes, change{old: &old, new: &updated, index: i})
+	}
+	return changes
+}
+
+// recordStep pushes a step made by the user, which invalidates the redo stack.
+func (m Model) recordStep(s step) Model {
+	m.undoStack = append(m.undoStack, s)
+	if len(m.undoStack) > maxUndo {
+		m.undoStack = slices.Delete(m.undoStack, 0, 1)
+	}
+	m.redoStack = nil
+	return m
+}
+
+func (m Model) undo() Model {
+	if len(m.undoStack) == 0 {
+		m.statusMsg = "Nothing to undo"
+		return m
+	}
+	s := m.undoStack[len(m.undoStack)-1]
+	m, err := m.applyStep(s, true)
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Undo failed: %v", err)
+		return m
+	}
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	m.redoStack = append(m.redoStack, s)
+	if s.label != "" {
+		m.statusMsg = "Undid " + s.label
+		return m
+	}
+	switch c := s.changes[0]; {
+	case c.old == nil:
+		m.statusMsg = "Removed " + c.new.Name
+	case c.new == nil:
+		m.statusMsg = "Restored " + c.old.Name
+	default:
+		m.statusMsg = "Reverted " + c.old.Name
+	}
+	return m
+}
+
+func (m Model) redo() Model {
+	if len(m.redoStack) == 0 {
+		m.statusMsg = "Nothing to redo"
+		return m
+	}
+	s := m.redoStack[len(m.redoStack)-1]
+	m, err := m.applyStep(s, false)
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Redo failed: %v", err)
+		return m
+	}
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	m.undoStack = append(m.undoStack, s)
+	if s.label != "" {
+		m.statusMsg = "Redid " + s.label
+		return m
+	}
+	switch c := s.changes[0]; {
+	case c.old == nil:
+		m.statusMsg = "Recreated " + c.new.Name
+	case c.new == nil:
+		m.statusMsg = "Deleted " + c.old.Name
+	default:
+		m.statusMsg = "Reapplied edit to " + c.new.Name
+	}
+	return m
+}
+
+// applyStep reverts s when reverse is set and reapplies it otherwise, with
+// a single save. Changes are undone last-first so indices line up.
+func (m Model) applyStep(s step, reverse bool) (Model, error) {
+	commands := slices.Clone(m.commands)
+	// Only deletions the user made go to the trash, and undoing one takes
+	// it out again; an undone create is simply gone.
+	var trashed []config.Command
+	var untrashed []string
+	var last *config.Command
+	for i := range s.changes {
+		c := s.changes[i]
+		from, to := c.old, c.new
+		if reverse {
+			c = s.changes[len(s.changes)-1-i]
+			from, to = c.new, c.old
+		}
+		var err error
+		if commands, err = applyChange(commands, from, to, c.index); err != nil {
+			return m, err
+		}
+		switch {
+		case c.new == nil && !reverse:
+			trashed = append(trashed, *from)
+		case c.new == nil:
+			untrashed = append(untrashed, to.Name)
+		}
+		if to != nil {
+			last = to
+		}
+	}
+
+	if err := m.cfg.Save(commands); err != nil {
+		return m, err
+	}
+	// Best effort: the change itself is saved.
+	if len(trashed) > 0 {
+		_ = m.cfg.MoveToTrash(trashed...)
+	}
+	if len(untrashed) > 0 {
+		_ = m.cfg.Untrash(untrashed...)
+	}
+	if from, to := s.renamed[0], s.renamed[1]; from != "" {
+		if reverse {
+			from, to = to, from
+		}
+		if _, err := m.cfg.RenameCategoryInfo(from, to); err != nil {
+			return m, err
+		}
+	}
+	m.commands = commands
+	m = m.refreshAfterMutation()
+	if last != nil {
+		if j := config.Find(m.filtered, last.Name); j >= 0 {
+			m.cursor = j
+			m = m.adjustScroll()
+		}
+	}
+	return m, nil
+}
+
+// applyChange replaces the command from with to in commands: from nil
+// inserts to at index, to nil deletes from. The command is looked up by
+// name, since reloads may have moved it.
+func applyChange(commands []config.Command, from, to *config.Command, index int) ([]config.Command, error) {
+	i := -1
+	if from != nil {
+		if i = config.Find(commands, from.Name); i < 0 {
+			return nil, fmt.Errorf("%s no longer exists", from.Name)
+		}
+	}
+	switch {
+	case to == nil:
+		commands = slices.Delete(commands, i, i+1)
+	case from == nil:
+		if err := config.Validate(commands, *to, -1); err != nil {
+			return nil, err
+		}
+		commands = slices.Insert(commands, min(index, len(commands)), *to)
+	default:
+		if err := config.Validate(commands, *to, i); err != nil {
+			return nil, err
+		}
+		commands[i] = *to
+	}
+	return commands, nil
+}
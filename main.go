@@ -1,11 +1,17 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 
 	"tb/internal/config"
+	"tb/internal/history"
+	"tb/internal/lint"
 	"tb/internal/shell"
 	"tb/internal/ui"
 
@@ -17,32 +23,61 @@ import (
 var version = "dev"
 
 func main() {
+	// Global flags come before the subcommand, e.g. tb --config ./tb.yaml init zsh
+	flags := flag.NewFlagSet("tb", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the config file (overrides $TB_CONFIG)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tb [--config <path>] [version | init <bash|zsh|fish> | add | rm | edit | list | show | import | export | trash | lint | restore]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+	args := flags.Args()
+
 	// CLI routing
-	if len(os.Args) >= 2 {
-		switch os.Args[1] {
+	if len(args) >= 1 {
+		switch args[0] {
 		case "version":
 			fmt.Printf("tb %s (%s)\n", version, runtime.Version())
 			return
 		case "init":
-			if len(os.Args) != 3 {
+			if len(args) != 2 {
 				fmt.Fprintln(os.Stderr, "Usage: tb init <bash|zsh|fish>")
 				os.Exit(1)
 			}
-			script, err := shell.InitScript(os.Args[2])
+			script, err := shell.InitScript(args[1])
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 			fmt.Print(script)
 			return
+		default:
+			if run, ok := fileCommands[args[0]]; ok {
+				exitOnError(run(*configPath, args[1:]))
+				return
+			}
+			run, ok := subcommands[args[0]]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+				flags.Usage()
+				os.Exit(1)
+			}
+			cfg, err := config.Load(*configPath)
+			if err != nil {
+				exitConfigError(*configPath, err)
+			}
+			for _, d := range lintErrors(*configPath) {
+				fmt.Fprintln(os.Stderr, d)
+			}
+			exitOnError(run(cfg, args[1:]))
+			return
 		}
 	}
 
 	// Default: launch TUI
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		exitConfigError(*configPath, err)
 	}
 
 	// Use stderr for all TUI rendering so it displays correctly even when
@@ -50,7 +85,18 @@ func main() {
 	// Also tell lipgloss to detect color support from stderr, not stdout.
 	lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(os.Stderr))
 
-	m := ui.New(cfg.Commands, cfg.Categories(), cfg.Keybindings)
+	// History only affects ordering, so a broken history file isn't fatal.
+	hist, _ := history.Load()
+
+	m := ui.New(cfg, hist)
+	if diags := lintErrors(*configPath); len(diags) > 0 {
+		d := diags[0]
+		msg := fmt.Sprintf("%s:%d: %s", filepath.Base(d.File), d.Line, d.Message)
+		if len(diags) > 1 {
+			msg += fmt.Sprintf(" (and %d more)", len(diags)-1)
+		}
+		m = m.WithStatus(msg + ", run tb lint")
+	}
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(os.Stderr))
 
 	finalModel, err := p.Run()
@@ -82,3 +128,41 @@ func main() {
 		}
 	}
 }
+
+// exitOnError prints a subcommand's error and exits non-zero. -h is not an error.
+func exitOnError(err error) {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
+
+// exitConfigError reports why the config failed to load and exits. Lint
+// diagnostics point at the offending line; the bare error is the fallback
+// when lint finds nothing more specific.
+func exitConfigError(configPath string, err error) {
+	if diags := lintErrors(configPath); len(diags) > 0 {
+		for _, d := range diags {
+			fmt.Fprintln(os.Stderr, d)
+		}
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+	os.Exit(1)
+}
+
+// lintErrors returns the lint errors in the config files, in file order. A
+// config can load and still have some, such as duplicate names.
+func lintErrors(configPath string) []lint.Diagnostic {
+	files, err := config.Files(configPath)
+	if err != nil {
+		return nil
+	}
+	diags, err := lint.Files(files)
+	if err != nil {
+		return nil
+	}
+	lint.Sort(diags, files)
+	return slices.DeleteFunc(diags, func(d lint.Diagnostic) bool { return d.Severity != lint.Error })
+}